@@ -0,0 +1,109 @@
+//
+// go.sh/cmd/gosh :: input.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/hattya/go.sh/editor"
+)
+
+// input is an io.RuneScanner which reads the commands a line at a time.
+// A line is read when all the runes of the previous line were read, so
+// that the parser never reads beyond the line which completes a command.
+//
+// If ed is not nil, the lines are read by the line editor, and a line
+// may consist of multiple lines which complete a command.
+type input struct {
+	r       io.ByteReader
+	w       io.Writer
+	ed      *editor.Editor
+	prompt  func(ps2 bool) string
+	verbose func() bool
+
+	line strings.Reader
+	ps2  bool
+	eof  bool
+}
+
+func (in *input) ReadRune() (rune, int, error) {
+	if in.line.Len() == 0 {
+		if in.eof {
+			return 0, 0, io.EOF
+		}
+		var s string
+		var err error
+		if in.ed != nil {
+			s, err = in.ed.ReadLine(in.ps2)
+		} else {
+			if in.prompt != nil {
+				io.WriteString(in.w, in.prompt(in.ps2))
+			}
+			s, err = in.readLine()
+		}
+		in.ps2 = true
+		switch {
+		case err == io.EOF:
+			in.eof = true
+			if s == "" {
+				return 0, 0, io.EOF
+			}
+		case err != nil:
+			return 0, 0, err
+		}
+		if in.verbose != nil && in.verbose() {
+			io.WriteString(in.w, s)
+		}
+		in.line.Reset(s)
+	}
+	return in.line.ReadRune()
+}
+
+func (in *input) UnreadRune() error {
+	return in.line.UnreadRune()
+}
+
+// readLine reads until the first occurrence of <newline> in the input.
+func (in *input) readLine() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := in.r.ReadByte()
+		if err != nil {
+			return b.String(), err
+		}
+		b.WriteByte(c)
+		if c == '\n' {
+			return b.String(), nil
+		}
+	}
+}
+
+// buffered reports whether the line has runes which are not read yet.
+func (in *input) buffered() bool {
+	return in.line.Len() > 0
+}
+
+// unbuffered is an io.ByteReader which reads a byte at a time from the
+// underlying io.Reader.
+type unbuffered struct {
+	r io.Reader
+}
+
+func (u unbuffered) ReadByte() (byte, error) {
+	var b [1]byte
+	for {
+		switch n, err := u.r.Read(b[:]); {
+		case n == 1:
+			return b[0], nil
+		case err != nil:
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,397 @@
+//
+// go.sh/cmd/gosh :: main.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+// Command gosh is a shell built on the parser and interp packages.
+//
+// Usage:
+//
+//	gosh [-abCefimnuvx] [-o option]... [file [argument...]]
+//	gosh -c [-abCefimnuvx] [-o option]... command_string [command_name [argument...]]
+//	gosh -s [-abCefimnuvx] [-o option]... [argument...]
+//
+// If neither the -c option nor a file operand is specified, the commands
+// are read from the standard input. The shell is interactive if the -i
+// option is specified, or the commands are read from the standard input
+// and both the standard input and standard error are terminals.
+//
+// When the shell is interactive and the standard input is a terminal, the
+// commands are read by the line editor with the key bindings of emacs, or
+// of vi if the vi option is enabled.
+//
+// An interactive shell records the commands in the history, which is
+// saved to the file named by the HISTFILE variable. If it is unset,
+// $HOME/.gosh_history is used.
+//
+// The line editor completes command names, pathnames, and variable names
+// with the <tab> key.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/editor"
+	"github.com/hattya/go.sh/interp"
+	"github.com/hattya/go.sh/parser"
+)
+
+const name = "gosh"
+
+func main() {
+	sh := &shell{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	os.Exit(sh.main(os.Args[1:]))
+}
+
+// shell represents an invocation of the shell.
+type shell struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	env         *interp.ExecEnv
+	interactive bool
+	tty         bool
+}
+
+// main parses the command line arguments, and executes the commands. It
+// returns the exit status of the shell.
+func (sh *shell) main(args []string) int {
+	var cmd, stdin bool
+	var opts []string
+	i := 0
+Options:
+	for ; i < len(args); i++ {
+		s := args[i]
+		switch {
+		case s == "--" || s == "-":
+			i++
+			break Options
+		case len(s) < 2 || (s[0] != '-' && s[0] != '+'):
+			break Options
+		}
+		for _, r := range s[1:] {
+			switch {
+			case s[0] == '-' && r == 'c':
+				cmd = true
+			case s[0] == '-' && r == 'i':
+				sh.interactive = true
+			case s[0] == '-' && r == 's':
+				stdin = true
+			case r == 'o':
+				if i+1 == len(args) {
+					return sh.usage(fmt.Sprintf("%co: option requires an argument", s[0]))
+				}
+				i++
+				opts = append(opts, s[:1]+"o", args[i])
+			default:
+				opts = append(opts, s[:1]+string(r))
+			}
+		}
+	}
+
+	var src io.ByteReader
+	argv0 := name
+	switch {
+	case cmd:
+		if i == len(args) {
+			return sh.usage("-c: option requires an argument")
+		}
+		src = strings.NewReader(args[i])
+		i++
+		if i < len(args) {
+			argv0 = args[i]
+			i++
+		}
+	case !stdin && i < len(args):
+		f, err := os.Open(args[i])
+		if err != nil {
+			fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+			if errors.Is(err, os.ErrNotExist) {
+				return 127
+			}
+			return 126
+		}
+		defer f.Close()
+		src = bufio.NewReader(f)
+		argv0 = args[i]
+		i++
+	default:
+		// read a byte at a time to leave the rest of the input to the
+		// utilities
+		src = unbuffered{sh.stdin}
+		sh.tty = editor.IsTerminal(sh.stdin) && editor.IsTerminal(sh.stderr)
+		if !sh.interactive {
+			sh.interactive = sh.tty
+		}
+	}
+
+	sh.env = interp.NewExecEnv(argv0, args[i:]...)
+	sh.env.Stdin = sh.stdin
+	sh.env.Stdout = sh.stdout
+	sh.env.Stderr = sh.stderr
+	if sh.interactive {
+		opts = append([]string{"-m"}, opts...)
+	}
+	if len(opts) > 0 {
+		if _, err := sh.env.Run(context.Background(), simpleCmd(append([]string{"set"}, opts...)...)); err != nil {
+			return sh.usage(err.Error())
+		}
+	}
+	if sh.interactive {
+		sh.env.History = interp.NewHistory()
+		if _, set := sh.env.Get("HISTFILE"); !set {
+			if v, _ := sh.env.Get("HOME"); v.Value != "" {
+				sh.env.Set("HISTFILE", filepath.Join(v.Value, "."+name+"_history"))
+			}
+		}
+		if err := sh.env.LoadHistory(); err != nil {
+			fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+		}
+	}
+	return sh.repl(src)
+}
+
+// usage writes the error message and the usage to the standard error, and
+// returns the exit status for it.
+func (sh *shell) usage(msg string) int {
+	fmt.Fprintf(sh.stderr, "%v: %v\n", name, msg)
+	fmt.Fprintf(sh.stderr, "usage: %v [-abCefimnuvx] [-o option]... [file [argument...]]\n", name)
+	fmt.Fprintf(sh.stderr, "       %v -c [-abCefimnuvx] [-o option]... command_string [command_name [argument...]]\n", name)
+	fmt.Fprintf(sh.stderr, "       %v -s [-abCefimnuvx] [-o option]... [argument...]\n", name)
+	return 2
+}
+
+// repl reads commands from src, and executes them until EOF or the exit
+// of the shell. When the shell is interactive, it prompts with PS1 for a
+// new command, and with PS2 while the command is incomplete. The commands
+// are read by the line editor if the standard input is a terminal.
+func (sh *shell) repl(src io.ByteReader) int {
+	in := &input{
+		r:       src,
+		w:       sh.stderr,
+		verbose: func() bool { return sh.env.Opts&interp.Verbose != 0 },
+	}
+	var sigs chan os.Signal
+	if sh.interactive {
+		in.prompt = sh.prompt
+		if sh.tty {
+			in.ed = editor.New(sh.stdin, sh.stderr)
+			in.ed.Prompt = sh.prompt
+			in.ed.Continue = sh.incomplete
+			in.ed.Edit = sh.edit
+			in.ed.History = sh.env.History
+			in.ed.Complete = sh.complete
+		}
+		// an interactive shell is not terminated by SIGINT and SIGTERM
+		sigs = make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+	}
+	pr, _ := parser.NewReader(in)
+
+	for {
+		in.ps2 = false
+		if in.ed != nil {
+			if sh.env.Opts&interp.Vi != 0 {
+				in.ed.Mode = editor.Vi
+			} else {
+				in.ed.Mode = editor.Emacs
+			}
+		}
+		cmds, _, err := parser.ParseCommands(sh.env, sh.env.Args[0], pr)
+		switch {
+		case errors.Is(err, editor.ErrInterrupted):
+			// discard the command
+		case err != nil:
+			fmt.Fprintln(sh.stderr, err)
+			if !sh.interactive {
+				return 2
+			}
+			// discard the rest of the line
+			for in.buffered() {
+				pr.ReadRune()
+			}
+		case sh.env.Opts&interp.NoExec != 0 && !sh.interactive:
+		default:
+			for _, cmd := range cmds {
+				if sh.interactive {
+					sh.env.AddHistory(cmd)
+				}
+				if status, exit := sh.run(cmd, sigs); exit {
+					return status
+				}
+			}
+		}
+
+		if in.eof {
+			if sh.interactive && sh.env.Opts&interp.IgnoreEOF != 0 && sh.tty {
+				fmt.Fprintln(sh.stderr, `Use "exit" to leave the shell.`)
+				in.eof = false
+				continue
+			}
+			break
+		}
+	}
+	// the implicit exit is not traced
+	sh.env.Opts &^= interp.XTrace
+	status, _ := sh.run(simpleCmd("exit"), nil)
+	return status
+}
+
+// run executes the command. It reports whether the shell exits.
+//
+// When the shell is interactive, the command is interrupted by SIGINT.
+func (sh *shell) run(cmd ast.Command, sigs chan os.Signal) (int, bool) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	if sigs != nil {
+		// discard the signals received while reading the command
+		for len(sigs) > 0 {
+			<-sigs
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case sig := <-sigs:
+					if sig == os.Interrupt {
+						cancel(interp.ErrInterrupted)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	status, err := sh.env.Run(ctx, cmd)
+	var ee *interp.ExitError
+	switch {
+	case err == nil:
+		if sigs != nil && status == 128+int(syscall.SIGINT) {
+			// the command was terminated by SIGINT
+			fmt.Fprintln(sh.stderr)
+		}
+	case errors.As(err, &ee):
+		return ee.Status, true
+	case errors.Is(context.Cause(ctx), interp.ErrInterrupted):
+		fmt.Fprintln(sh.stderr)
+	default:
+		fmt.Fprintln(sh.stderr, err)
+		if !sh.interactive {
+			return 2, true
+		}
+	}
+	return status, false
+}
+
+// prompt returns the expansion of PS1 or PS2. The status of the jobs is
+// reported before PS1.
+func (sh *shell) prompt(ps2 bool) string {
+	if ps2 {
+		return sh.env.Prompt(context.Background(), "PS2")
+	}
+	sh.env.ReportJobs()
+	return sh.env.Prompt(context.Background(), "PS1")
+}
+
+// incomplete reports whether text needs more lines to complete a
+// command.
+func (sh *shell) incomplete(text string) bool {
+	r := &eofReader{Reader: strings.NewReader(text)}
+	_, _, err := parser.ParseCommands(sh.env, sh.env.Args[0], r)
+	return err != nil && r.eof
+}
+
+// complete returns the candidates for the word before the byte offset pos
+// of text.
+func (sh *shell) complete(text string, pos int) (int, []string) {
+	c := sh.env.Complete(context.Background(), text, pos)
+	return c.Start, c.Candidates
+}
+
+// eofReader is a strings.Reader which records whether EOF was reached.
+type eofReader struct {
+	*strings.Reader
+	eof bool
+}
+
+func (r *eofReader) ReadRune() (rune, int, error) {
+	c, size, err := r.Reader.ReadRune()
+	if err == io.EOF {
+		r.eof = true
+	}
+	return c, size, err
+}
+
+// edit edits text with the editor which is specified by the VISUAL or
+// EDITOR variable.
+func (sh *shell) edit(text string) (string, error) {
+	f, err := os.CreateTemp("", name+"-*.sh")
+	if err != nil {
+		fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+		return "", err
+	}
+
+	cmd, _, err := parser.ParseCommand(name, "${VISUAL:-${EDITOR:-vi}} '"+strings.ReplaceAll(f.Name(), "'", `'\''`)+"'")
+	if err != nil {
+		return "", err
+	}
+	switch status, err := sh.env.Run(context.Background(), cmd); {
+	case err != nil:
+		fmt.Fprintln(sh.stderr, err)
+		return "", err
+	case status != 0:
+		err = fmt.Errorf("editor exited with status %v", status)
+		fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+		return "", err
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		fmt.Fprintf(sh.stderr, "%v: %v\n", name, err)
+		return "", err
+	}
+	return string(b), nil
+}
+
+// simpleCmd returns a simple command which consists of the quoted args.
+func simpleCmd(args ...string) ast.Command {
+	x := new(ast.SimpleCmd)
+	for _, s := range args {
+		x.Args = append(x.Args, ast.Word{
+			&ast.Quote{
+				Tok:   `'`,
+				Value: ast.Word{&ast.Lit{Value: s}},
+			},
+		})
+	}
+	return &ast.Cmd{Expr: x}
+}
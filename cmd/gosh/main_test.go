@@ -0,0 +1,196 @@
+//
+// go.sh/cmd/gosh :: main_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var shellTests = []struct {
+	args   []string
+	stdin  string
+	stdout string
+	stderr string
+	status int
+}{
+	// -c
+	{[]string{"-c", "echo $0 $#"}, "", "gosh 0\n", "", 0},
+	{[]string{"-c", "echo $0 $@", "sh", "foo", "bar"}, "", "sh foo bar\n", "", 0},
+	{[]string{"-c", "exit 3"}, "", "", "", 3},
+	{[]string{"-c", "echo foo\necho bar"}, "", "foo\nbar\n", "", 0},
+	{[]string{"-c", "echo ${"}, "", "", "gosh:1:6: syntax error: reached EOF while looking for matching '}'\n", 2},
+	{[]string{"-ec", "false; echo foo"}, "", "", "", 1},
+	{[]string{"-o", "errexit", "-c", "false; echo foo"}, "", "", "", 1},
+	{[]string{"-xc", "echo foo"}, "", "foo\n", "+ echo foo\n", 0},
+	// standard input
+	{nil, "echo foo\nexit 3\necho bar\n", "foo\n", "", 3},
+	{nil, "false", "", "", 1},
+	{nil, "echo foo\necho ${\necho bar\n", "foo\n", "gosh:2:6: syntax error: invalid parameter expansion\n", 2},
+	{nil, "echo ${V?unset}\necho foo\n", "", "$V: unset\n", 2},
+	{nil, "echo $LINENO\n\necho $LINENO\n", "1\n3\n", "", 0},
+	{nil, "alias ll='echo ll'\nll\n", "ll\n", "", 0},
+	{nil, "trap 'echo exit' EXIT\necho foo\n", "foo\nexit\n", "", 0},
+	{[]string{"-n"}, "echo foo\n", "", "", 0},
+	{[]string{"-v"}, "echo foo\n", "foo\n", "echo foo\n", 0},
+	{[]string{"-s", "foo", "bar"}, "echo $0 $@\n", "gosh foo bar\n", "", 0},
+	{[]string{"-"}, "echo $0\n", "gosh\n", "", 0},
+	// interactive
+	{[]string{"-i"}, "echo foo\n", "foo\n", "% % ", 0},
+	{[]string{"-i"}, "echo 'foo\nbar'\n", "foo\nbar\n", "% > % ", 0},
+	{[]string{"-i"}, "if true\nthen echo foo\nfi\n", "foo\n", "% > > % ", 0},
+	{[]string{"-i"}, "echo ${\necho foo\n", "foo\n", "% gosh:1:6: syntax error: invalid parameter expansion\n% % ", 0},
+	{[]string{"-i"}, "echo ${V?unset}\necho foo\n", "foo\n", "% $V: unset\n% % ", 0},
+	{[]string{"-i"}, "exit 3\necho foo\n", "", "% ", 3},
+	{[]string{"-i"}, "PS1='$V> '; V=foo\n", "", "% foo> ", 0},
+	{[]string{"-i"}, "PS1='!% '\necho foo\n", "foo\n", "% 2% 3% ", 0},
+	{[]string{"-i"}, "echo foo\nfc -l\n", "foo\n1\techo foo\n", "% % % ", 0},
+	{[]string{"-i"}, "echo foo\nfc -s foo=bar\n", "foo\nbar\n", "% % echo bar\n% ", 0},
+	{[]string{"-i", "-o", "nolog"}, "f() { :; }\nfc -l\n", "", "% % fc: history is empty\n% ", 1},
+	// usage
+	{[]string{"-c"}, "", "", "gosh: -c: option requires an argument\n" + usage, 2},
+	{[]string{"-o"}, "", "", "gosh: -o: option requires an argument\n" + usage, 2},
+	{[]string{"-Z"}, "", "", "gosh: set: -Z: invalid option\n" + usage, 2},
+	{[]string{"-o", "foo"}, "", "", "gosh: set: foo: invalid option name\n" + usage, 2},
+}
+
+const usage = `usage: gosh [-abCefimnuvx] [-o option]... [file [argument...]]
+       gosh -c [-abCefimnuvx] [-o option]... command_string [command_name [argument...]]
+       gosh -s [-abCefimnuvx] [-o option]... [argument...]
+`
+
+func TestShell(t *testing.T) {
+	t.Setenv("PS1", "% ")
+	t.Setenv("PS2", "> ")
+	t.Setenv("HISTFILE", "")
+	for _, tt := range shellTests {
+		var stdout, stderr strings.Builder
+		sh := &shell{
+			stdin:  strings.NewReader(tt.stdin),
+			stdout: &stdout,
+			stderr: &stderr,
+		}
+		if g, e := sh.main(tt.args), tt.status; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.args, e, g)
+		}
+		if g, e := stdout.String(), tt.stdout; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.args, e, g)
+		}
+		if g, e := stderr.String(), tt.stderr; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.args, e, g)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script")
+	if err := os.WriteFile(script, []byte("echo $0 $@\nexit 3\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	sh := &shell{
+		stdin:  strings.NewReader(""),
+		stdout: &stdout,
+		stderr: &stderr,
+	}
+	if g, e := sh.main([]string{script, "foo"}), 3; g != e {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+	if g, e := stdout.String(), script+" foo\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if g, e := sh.main([]string{filepath.Join(dir, "go.sh-not-found")}), 127; g != e {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+	if stderr.Len() == 0 {
+		t.Error("expected error")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PS1", "% ")
+	t.Setenv("HOME", dir)
+	t.Setenv("HISTFILE", "")
+	os.Unsetenv("HISTFILE")
+
+	for _, tt := range []struct {
+		stdin, stdout string
+	}{
+		{"echo foo\n", "foo\n"},
+		{"fc -l\n", "1\techo foo\n"},
+	} {
+		var stdout, stderr strings.Builder
+		sh := &shell{
+			stdin:  strings.NewReader(tt.stdin),
+			stdout: &stdout,
+			stderr: &stderr,
+		}
+		if g, e := sh.main([]string{"-i"}), 0; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.stdin, e, g)
+		}
+		if g, e := stdout.String(), tt.stdout; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.stdin, e, g)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, ".gosh_history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, e := string(b), "echo foo\nfc -l\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+var incompleteTests = []struct {
+	src        string
+	incomplete bool
+}{
+	{"echo foo\n", false},
+	{"echo foo; echo bar\n", false},
+	{"echo )\n", false},
+	{"if true\n", true},
+	{"if true\nthen echo foo\n", true},
+	{"if true\nthen echo foo\nfi\n", false},
+	{"echo 'foo\n", true},
+	{"echo \"foo\n", true},
+	{"echo ${foo:-bar\n", true},
+	{"echo $(foo\n", true},
+	{"echo foo |\n", true},
+	{"cat <<EOF\n", true},
+	{"cat <<EOF\nfoo\nEOF\n", false},
+}
+
+func TestIncomplete(t *testing.T) {
+	var stderr strings.Builder
+	sh := &shell{
+		stdin:  strings.NewReader(""),
+		stdout: io.Discard,
+		stderr: &stderr,
+	}
+	sh.env = interp.NewExecEnv(name)
+	for _, tt := range incompleteTests {
+		if g, e := sh.incomplete(tt.src), tt.incomplete; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.src, e, g)
+		}
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("unexpected output: %q", stderr.String())
+	}
+}
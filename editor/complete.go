@@ -0,0 +1,144 @@
+//
+// go.sh/editor :: complete.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// candidates returns the index of the word which is completed and the
+// candidates for it.
+func (ed *Editor) candidates() (int, []string) {
+	if ed.Complete == nil {
+		return 0, nil
+	}
+	s := string(ed.buf)
+	start, list := ed.Complete(s, len(string(ed.buf[:ed.pos])))
+	start = min(max(start, 0), len(s))
+	return utf8.RuneCountInString(s[:start]), list
+}
+
+// complete completes the word before the cursor. A unique candidate
+// replaces the word and is followed by a <space> unless it is a
+// directory. Otherwise the word is extended to the longest common prefix
+// of the candidates, or they are listed if it cannot be extended.
+func (ed *Editor) complete() error {
+	start, list := ed.candidates()
+	if len(list) == 0 {
+		return errContinue
+	}
+	word := string(ed.buf[start:ed.pos])
+	s := list[0]
+	if len(list) == 1 {
+		if !strings.HasSuffix(s, "/") {
+			s += " "
+		}
+	} else {
+		for _, c := range list[1:] {
+			s = commonPrefix(s, c)
+		}
+		if len(s) <= len(word) {
+			ed.list(word, list)
+			return nil
+		}
+	}
+	ed.replaceWord(start, s)
+	return nil
+}
+
+// completeAll replaces the word before the cursor with all of the
+// candidates.
+func (ed *Editor) completeAll() error {
+	start, list := ed.candidates()
+	if len(list) == 0 {
+		return errContinue
+	}
+	ed.replaceWord(start, strings.Join(list, " ")+" ")
+	return nil
+}
+
+// listCandidates lists the candidates for the word before the cursor.
+func (ed *Editor) listCandidates() error {
+	start, list := ed.candidates()
+	if len(list) == 0 {
+		return errContinue
+	}
+	ed.list(string(ed.buf[start:ed.pos]), list)
+	return nil
+}
+
+// replaceWord replaces the runes in the range [start, pos) with s, and
+// moves the cursor after it.
+func (ed *Editor) replaceWord(start int, s string) {
+	if ed.Mode == Emacs || !ed.insert {
+		ed.save()
+	}
+	ed.delete(start, ed.pos, false)
+	ed.insertRunes([]rune(s)...)
+}
+
+// list writes the candidates in columns below the edit line. The
+// directory part of the word is omitted from the candidates.
+func (ed *Editor) list(word string, list []string) {
+	dir := word[:strings.LastIndexByte(word, '/')+1]
+	names := make([]string, len(list))
+	w := 0
+	for i, s := range list {
+		names[i] = strings.TrimPrefix(s, dir)
+		w = max(w, strWidth(names[i]))
+	}
+	w += 2
+	cols := max(ed.termWidth()/w, 1)
+	rows := (len(names) + cols - 1) / cols
+
+	pos := ed.pos
+	ed.pos = len(ed.buf)
+	ed.refresh()
+	ed.pos = pos
+	var b strings.Builder
+	b.WriteString("\r\n")
+	for r := range rows {
+		for c := range cols {
+			i := c*rows + r
+			if i >= len(names) {
+				break
+			}
+			b.WriteString(names[i])
+			if c < cols-1 && i+rows < len(names) {
+				b.WriteString(strings.Repeat(" ", w-strWidth(names[i])))
+			}
+		}
+		b.WriteString("\r\n")
+	}
+	io.WriteString(ed.out, b.String())
+	ed.row = 0
+}
+
+// commonPrefix returns the longest common prefix of a and b.
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	// do not split a rune
+	for i > 0 && i < len(a) && !utf8.RuneStart(a[i]) {
+		i--
+	}
+	return a[:i]
+}
+
+// strWidth returns the number of the columns which s occupies.
+func strWidth(s string) (w int) {
+	for _, r := range s {
+		w += runeWidth(r, w)
+	}
+	return
+}
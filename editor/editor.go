@@ -0,0 +1,365 @@
+//
+// go.sh/editor :: editor.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+// Package editor implements a line editor for the interactive shell.
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"slices"
+)
+
+// ErrInterrupted is returned by ReadLine when the editing is interrupted
+// by the INTR character.
+var ErrInterrupted = errors.New("interrupted")
+
+// Mode represents an editing mode.
+type Mode int
+
+const (
+	// Emacs is the editing mode with the key bindings of emacs.
+	Emacs Mode = iota
+	// Vi is the editing mode with the key bindings of vi, which is
+	// specified by POSIX.
+	Vi
+)
+
+// Editor is a line editor. When the input is a terminal, it is put into
+// raw mode while a line is edited.
+type Editor struct {
+	// Mode is the editing mode.
+	Mode Mode
+
+	// Prompt returns the prompt for the first line of a command if ps2
+	// is false, or for the continuation lines otherwise.
+	Prompt func(ps2 bool) string
+
+	// Continue reports whether text needs more lines to complete a
+	// command. If it returns true, the accepted line is continued on
+	// the next line, and all the lines can be edited as a whole.
+	Continue func(text string) bool
+
+	// Edit edits text with an external editor, and returns the result
+	// which is accepted without further editing. If it returns an error,
+	// the editing is continued with the current edit line.
+	Edit func(text string) (string, error)
+
+	// History is the command history which is recalled by the key
+	// bindings. If it is nil, the history is not available.
+	History History
+
+	// Complete returns the candidates for the word before the byte
+	// offset pos of text, and the byte offset of the beginning of the
+	// word. The candidates replace the text in the range [start, pos).
+	// If it is nil, the completion is not available.
+	Complete func(text string, pos int) (start int, candidates []string)
+
+	in    io.Reader
+	out   io.Writer
+	r     *bufio.Reader
+	reset func()
+
+	buf      []rune
+	pos      int
+	ps1, ps2 string
+	hasPS2   bool
+	cont     bool
+	row      int
+	kill     []rune
+	undo     []snapshot
+	typing   bool
+	insert   bool
+	replace  bool
+	find     [2]rune
+	hist     int
+	line     []rune
+	search   string
+	backward bool
+}
+
+// snapshot represents a state of the edit line.
+type snapshot struct {
+	buf []rune
+	pos int
+}
+
+// New returns a new Editor which reads keys from in and writes the edit
+// line to out.
+func New(in io.Reader, out io.Writer) *Editor {
+	return &Editor{
+		in:  in,
+		out: out,
+		r:   bufio.NewReader(in),
+	}
+}
+
+// ReadLine reads a line, and returns it with the trailing <newline>. If
+// ps2 is true, the line is a continuation of the previous one.
+//
+// When the edit line is empty, the EOF character returns io.EOF.
+func (ed *Editor) ReadLine(ps2 bool) (string, error) {
+	ed.buf = ed.buf[:0]
+	ed.pos = 0
+	ed.cont = ps2
+	ed.ps1, ed.ps2 = "", ""
+	ed.row = 0
+	ed.undo = ed.undo[:0]
+	ed.typing = false
+	ed.insert = true
+	ed.replace = false
+	ed.hasPS2 = false
+	ed.hist = ed.histLen()
+	ed.line = nil
+	if ed.Prompt != nil {
+		ed.ps1 = ed.Prompt(ps2)
+		if ps2 {
+			ed.ps2, ed.hasPS2 = ed.ps1, true
+		}
+	}
+
+	if ed.Mode == Vi {
+		// for U
+		ed.save()
+	}
+
+	if err := ed.makeRaw(); err != nil {
+		return "", err
+	}
+	defer ed.restore()
+	ed.refresh()
+	for {
+		k, err := ed.readKey()
+		switch {
+		case err == io.EOF && len(ed.buf) > 0:
+			return ed.accept()
+		case err != nil:
+			return "", ed.abort(err)
+		}
+
+		var done bool
+		if ed.Mode == Vi {
+			done, err = ed.vi(k)
+		} else {
+			done, err = ed.emacs(k)
+		}
+		switch {
+		case errors.Is(err, errContinue):
+			ed.bell()
+		case err != nil:
+			return "", ed.abort(err)
+		case done:
+			return ed.accept()
+		}
+		ed.refresh()
+	}
+}
+
+// errContinue is returned by the key bindings when the key cannot be
+// applied to the edit line.
+var errContinue = errors.New("continue")
+
+// makeRaw puts the terminal into raw mode.
+func (ed *Editor) makeRaw() error {
+	if f, ok := ed.in.(*os.File); ok && IsTerminal(f) {
+		reset, err := makeRaw(f, ed.out)
+		if err != nil {
+			return err
+		}
+		ed.reset = reset
+	}
+	return nil
+}
+
+// restore restores the terminal from raw mode.
+func (ed *Editor) restore() {
+	if ed.reset != nil {
+		ed.reset()
+		ed.reset = nil
+	}
+}
+
+// accept moves the cursor to the end of the edit line, and returns it.
+func (ed *Editor) accept() (string, error) {
+	ed.pos = len(ed.buf)
+	ed.refresh()
+	io.WriteString(ed.out, "\r\n")
+	return string(ed.buf) + "\n", nil
+}
+
+// abort moves the cursor to the next line of the edit line, and returns
+// err.
+func (ed *Editor) abort(err error) error {
+	if errors.Is(err, ErrInterrupted) {
+		ed.pos = len(ed.buf)
+		ed.refresh()
+		io.WriteString(ed.out, "^C")
+	}
+	io.WriteString(ed.out, "\r\n")
+	return err
+}
+
+// newline inserts a <newline> at the end of the edit line if the command
+// is incomplete. It reports whether the edit line is continued.
+func (ed *Editor) newline() bool {
+	if ed.cont || ed.Continue == nil || !ed.Continue(string(ed.buf)+"\n") {
+		return false
+	}
+	ed.pos = len(ed.buf)
+	ed.buf = append(ed.buf, '\n')
+	ed.pos++
+	return true
+}
+
+// prompt2 returns the prompt for the continuation lines.
+func (ed *Editor) prompt2() string {
+	if !ed.hasPS2 && ed.Prompt != nil {
+		ed.ps2, ed.hasPS2 = ed.Prompt(true), true
+	}
+	return ed.ps2
+}
+
+// edit edits the edit line with the external editor.
+func (ed *Editor) edit() (bool, error) {
+	if ed.Edit == nil {
+		return false, errContinue
+	}
+	ed.pos = len(ed.buf)
+	ed.refresh()
+	io.WriteString(ed.out, "\r\n")
+	ed.restore()
+	s, err := ed.Edit(string(ed.buf))
+	if merr := ed.makeRaw(); merr != nil {
+		return false, merr
+	}
+	ed.row = 0
+	if err != nil {
+		// the error is reported by Edit
+		return false, errContinue
+	}
+	ed.save()
+	ed.buf = append(ed.buf[:0], []rune(trimNewline(s))...)
+	ed.pos = len(ed.buf)
+	return true, nil
+}
+
+// trimNewline removes a trailing <newline> from s.
+func trimNewline(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// save saves the current edit line for undo.
+func (ed *Editor) save() {
+	ed.undo = append(ed.undo, snapshot{
+		buf: slices.Clone(ed.buf),
+		pos: ed.pos,
+	})
+}
+
+// revert restores the edit line which was saved last.
+func (ed *Editor) revert() error {
+	if len(ed.undo) == 0 {
+		return errContinue
+	}
+	s := ed.undo[len(ed.undo)-1]
+	ed.undo = ed.undo[:len(ed.undo)-1]
+	ed.buf = append(ed.buf[:0], s.buf...)
+	ed.pos = min(s.pos, len(ed.buf))
+	return nil
+}
+
+// bell rings the bell.
+func (ed *Editor) bell() {
+	io.WriteString(ed.out, "\a")
+}
+
+// insertRunes inserts rs at the cursor, and moves the cursor after them.
+func (ed *Editor) insertRunes(rs ...rune) {
+	ed.buf = slices.Insert(ed.buf, ed.pos, rs...)
+	ed.pos += len(rs)
+}
+
+// overwrite replaces the rune at the cursor with r unless the cursor is
+// at the end of the line, and moves the cursor after it.
+func (ed *Editor) overwrite(r rune) {
+	if ed.pos < ed.eol(ed.pos) {
+		ed.buf[ed.pos] = r
+		ed.pos++
+	} else {
+		ed.insertRunes(r)
+	}
+}
+
+// delete deletes the runes in the range [i, j), and saves them to the
+// kill buffer if kill is true. The cursor is moved to i.
+func (ed *Editor) delete(i, j int, kill bool) {
+	if i > j {
+		i, j = j, i
+	}
+	i = max(i, 0)
+	j = min(j, len(ed.buf))
+	ed.pos = i
+	if i == j {
+		return
+	}
+	if kill {
+		ed.kill = slices.Clone(ed.buf[i:j])
+	}
+	ed.buf = slices.Delete(ed.buf, i, j)
+}
+
+// bol returns the index of the beginning of the line which contains i.
+func (ed *Editor) bol(i int) int {
+	for i > 0 && ed.buf[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+// eol returns the index of the end of the line which contains i.
+func (ed *Editor) eol(i int) int {
+	for i < len(ed.buf) && ed.buf[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// lineUp moves the cursor to the previous line of the edit line. It
+// reports whether the cursor was moved.
+func (ed *Editor) lineUp() bool {
+	bol := ed.bol(ed.pos)
+	if bol == 0 {
+		return false
+	}
+	col := ed.pos - bol
+	prev := ed.bol(bol - 1)
+	ed.pos = min(prev+col, bol-1)
+	return true
+}
+
+// lineDown moves the cursor to the next line of the edit line. It
+// reports whether the cursor was moved.
+func (ed *Editor) lineDown() bool {
+	eol := ed.eol(ed.pos)
+	if eol == len(ed.buf) {
+		return false
+	}
+	col := ed.pos - ed.bol(ed.pos)
+	ed.pos = min(eol+1+col, ed.eol(eol+1))
+	return true
+}
+
+// isBlank reports whether r is a <blank> or a <newline>.
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n'
+}
@@ -0,0 +1,145 @@
+//
+// go.sh/editor :: editor_linux_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/hattya/go.sh/editor"
+)
+
+var ptyTests = []struct {
+	keys string
+	line string
+	err  error
+	out  string
+}{
+	{"echo foo\r", "echo foo\n", nil, "$ echo foo\r\x1b[10C\r\r\n"},
+	{"abcdefghijklmnopqr\r", "abcdefghijklmnopqr\n", nil, "$ abcdefghijklmnopqr\r\r\n\r\r\r\n"},
+	{"foo\x03", "", editor.ErrInterrupted, "$ foo\r\x1b[5C^C\r\r\n"},
+	{"\x04", "", io.EOF, "$ \r\x1b[2C\r\r\n"},
+}
+
+func TestPTY(t *testing.T) {
+	for _, tt := range ptyTests {
+		ptm, pts := openPTY(t)
+		ws := [4]uint16{24, 20}
+		if err := ioctl(ptm, syscall.TIOCSWINSZ, unsafe.Pointer(&ws)); err != nil {
+			t.Fatal(err)
+		}
+		var before, after syscall.Termios
+		if err := ioctl(pts, syscall.TCGETS, unsafe.Pointer(&before)); err != nil {
+			t.Fatal(err)
+		}
+
+		ed := editor.New(pts, pts)
+		ed.Prompt = func(bool) string { return "$ " }
+		type result struct {
+			line string
+			err  error
+		}
+		done := make(chan result)
+		go func() {
+			line, err := ed.ReadLine(false)
+			done <- result{line, err}
+		}()
+		// wait for the prompt which is written in raw mode
+		var out strings.Builder
+		ptm.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for b := make([]byte, 256); !strings.Contains(out.String(), "$ \r\x1b[2C"); {
+			n, err := ptm.Read(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.Write(b[:n])
+		}
+		if _, err := ptm.WriteString(tt.keys); err != nil {
+			t.Fatal(err)
+		}
+		var r result
+		select {
+		case r = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%q: timed out", tt.keys)
+		}
+		switch {
+		case r.err != tt.err:
+			t.Errorf("%q: expected error %v, got %v", tt.keys, tt.err, r.err)
+		case r.line != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, r.line)
+		}
+
+		if err := ioctl(pts, syscall.TCGETS, unsafe.Pointer(&after)); err != nil {
+			t.Fatal(err)
+		}
+		if before != after {
+			t.Errorf("%q: terminal is not restored", tt.keys)
+		}
+
+		pts.Close()
+		ptm.SetReadDeadline(time.Now().Add(time.Second))
+		b, _ := io.ReadAll(ptm)
+		out.Write(b)
+		if g := out.String(); !strings.HasSuffix(g, tt.out) {
+			t.Errorf("%q: expected suffix %q, got %q", tt.keys, tt.out, g)
+		}
+		ptm.Close()
+	}
+}
+
+func openPTY(t *testing.T) (*os.File, *os.File) {
+	t.Helper()
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	var unlock int32
+	if err := ioctl(ptm, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		ptm.Close()
+		t.Fatal(err)
+	}
+	var n uint32
+	if err := ioctl(ptm, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		ptm.Close()
+		t.Fatal(err)
+	}
+	pts, err := os.OpenFile(fmt.Sprintf("/dev/pts/%v", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		t.Fatal(err)
+	}
+	if !editor.IsTerminal(pts) {
+		t.Fatal("expected terminal")
+	}
+	return ptm, pts
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
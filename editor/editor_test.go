@@ -0,0 +1,397 @@
+//
+// go.sh/editor :: editor_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hattya/go.sh/editor"
+)
+
+var emacsTests = []struct {
+	keys, line string
+}{
+	{"echo foo\r", "echo foo\n"},
+	{"echo foo\n", "echo foo\n"},
+	{"echo foo", "echo foo\n"},
+	{"echo foo\x01# \r", "# echo foo\n"},
+	{"foo\x01\x05 bar\r", "foo bar\n"},
+	{"foo\x1b[H\x1b[F bar\r", "foo bar\n"},
+	{"foo bar\x02\x02\x02\x0b\r", "foo \n"},
+	{"foo bar\x1b[D\x1b[D\x1b[D\x1b[C\x0b\r", "foo b\n"},
+	{"foo bar\x01\x06\x06\x0b\r", "fo\n"},
+	{"foo bar\x01\x1bf\x0b\r", "foo\n"},
+	{"foo bar\x1bb\x0b\r", "foo \n"},
+	{"foo bar\x17\r", "foo \n"},
+	{"foo bar\x17\x19\x19\r", "foo barbar\n"},
+	{"foo bar\x1b\x7f\r", "foo \n"},
+	{"foo bar\x01\x1bd\r", " bar\n"},
+	{"foo bar\x02\x02\x15\r", "ar\n"},
+	{"foo\x7f\x7f\r", "f\n"},
+	{"foo\x08\r", "fo\n"},
+	{"foo\x01\x04\r", "oo\n"},
+	{"foo\x01\x1b[3~\r", "oo\n"},
+	{"foo\x04\r", "foo\n"},
+	{"ab\x14\r", "ba\n"},
+	{"abc\x01\x06\x14\r", "bac\n"},
+	{"foo bar\x1f\r", "\n"},
+	{"foo\x17bar\x1f\r", "\n"},
+	{"foo\x17bar\x1f\x1f\r", "foo\n"},
+	{"\x16\x01\r", "\x01\n"},
+	{"foo\x1b\rbar\r", "foo\nbar\n"},
+	{"foo\x1b\rbar\x10\x0b\x0b\r", "foo\n"},
+	{"foo\x1b\rbar\x10\x0e\x15\r", "foo\n\n"},
+	{"foo\x0c\r", "foo\n"},
+	{"foo\x18\x05\r", "foo\n"},
+}
+
+func TestEmacs(t *testing.T) {
+	for _, tt := range emacsTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		switch g, err := ed.ReadLine(false); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.keys, err)
+		case g != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, g)
+		}
+	}
+}
+
+var viTests = []struct {
+	keys, line string
+}{
+	{"echo foo\r", "echo foo\n"},
+	{"echo foo\x1b\r", "echo foo\n"},
+	{"foo bar\x1bbx\r", "foo ar\n"},
+	{"foo bar\x1b0dw\r", "bar\n"},
+	{"foo bar baz\x1b02dw\r", "baz\n"},
+	{"foo bar baz\x1b0d2w\r", "baz\n"},
+	{"foo bar\x1b0wdw\r", "foo \n"},
+	{"foo bar\x1b0de\r", " bar\n"},
+	{"foo bar\x1bdb\r", "foo r\n"},
+	{"foo bar\x1b0d$\r", "\n"},
+	{"foo bar\x1bd0\r", "r\n"},
+	{"foo bar\x1bdd\r", "\n"},
+	{"foo bar\x1b0cwbaz\x1b\r", "baz bar\n"},
+	{"foo bar\x1b0ccbaz\r", "baz\n"},
+	{"foo bar\x1b0wD\r", "foo \n"},
+	{"foo bar\x1b0wCbaz\r", "foo baz\n"},
+	{"foo\x1bSbar\r", "bar\n"},
+	{"foo bar\x1b0yep\r", "ffoooo bar\n"},
+	{"foo bar\x1b0yeP\r", "foofoo bar\n"},
+	{"foo\x1bxP\r", "foo\n"},
+	{"foo\x1bx2p\r", "fooo\n"},
+	{"abc\x1b0~~\r", "ABc\n"},
+	{"abc\x1b03~\r", "ABC\n"},
+	{"abc\x1b0rx\r", "xbc\n"},
+	{"abc\x1b03rx\r", "xxx\n"},
+	{"abc\x1b04rx\r", "abc\n"},
+	{"abc\x1bhhRxy\x1b\r", "xyc\n"},
+	{"abc\x1bIx\x1bAy\r", "xabcy\n"},
+	{"  abc\x1bIx\r", "  xabc\n"},
+	{"abc\x1b0ax\r", "axbc\n"},
+	{"abcdef\x1b03x\r", "def\n"},
+	{"abcdef\x1b3X\r", "abf\n"},
+	{"abc\x1b02|x\r", "ac\n"},
+	{"abc\x1b0$x\r", "ab\n"},
+	{"abc\x1b0llhx\r", "ac\n"},
+	{"abc\x1b0 x\r", "ac\n"},
+	{"  abc\x1b0^x\r", "  bc\n"},
+	{"a b c\x1b0fcix\r", "a b xc\n"},
+	{"a b c\x1b0tcix\r", "a bx c\n"},
+	{"a b c\x1bFaax\r", "ax b c\n"},
+	{"a b c\x1bTaix\r", "ax b c\n"},
+	{"a b a b\x1b0fb;ix\r", "a b a xb\n"},
+	{"a b a b\x1bFa;,ix\r", "a b xa b\n"},
+	{"a-b c\x1b0wx\r", "ab c\n"},
+	{"a-b c\x1b0Wx\r", "a-b \n"},
+	{"a-b c\x1bBx\r", "-b c\n"},
+	{"a-b c\x1b0Ex\r", "a- c\n"},
+	{"abc\x1bxu\r", "abc\n"},
+	{"abc\x1bxxU\r", "\n"},
+	{"abc\x1bxxUu\r", "a\n"},
+	{"abc\x1b#", "#abc\n"},
+	{"abc\x1bix\x7f\r", "abc\n"},
+	{"foo bar\x17\r", "foo \n"},
+	{"foo bar\x15baz\r", "baz\n"},
+	{"\x16\x1b\r", "\x1b\n"},
+	{"abc\x1b[D\x1b[D\x1b[3~\r", "ac\n"},
+}
+
+func TestVi(t *testing.T) {
+	for _, tt := range viTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		ed.Mode = editor.Vi
+		switch g, err := ed.ReadLine(false); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.keys, err)
+		case g != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, g)
+		}
+	}
+}
+
+var continueTests = []struct {
+	mode editor.Mode
+	keys string
+	line string
+}{
+	{editor.Emacs, "if\rfi\r", "if\nfi\n"},
+	{editor.Emacs, "if\rfi\x10\x05 true\r", "if true\nfi\n"},
+	{editor.Emacs, "if\rfi\x10\x10\r", "if\nfi\n"},
+	{editor.Vi, "if\rfi\r", "if\nfi\n"},
+	{editor.Vi, "if\rfi\x1bkA true\r", "if true\nfi\n"},
+	{editor.Vi, "if\rif\rfi\rfi\x1b3kA true\r", "if true\nif\nfi\nfi\n"},
+	{editor.Vi, "if\rfoo\rfi\x1bkdd\r", "if\nfi\n"},
+}
+
+func TestContinue(t *testing.T) {
+	var ps2 int
+	for _, tt := range continueTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		ed.Mode = tt.mode
+		ed.Prompt = func(b bool) string {
+			if b {
+				ps2++
+			}
+			return "$ "
+		}
+		ed.Continue = func(s string) bool {
+			return strings.Count(s, "if") > strings.Count(s, "fi")
+		}
+		switch g, err := ed.ReadLine(false); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.keys, err)
+		case g != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, g)
+		}
+	}
+	if ps2 == 0 {
+		t.Error("PS2 is not used")
+	}
+	t.Run("PS2", func(t *testing.T) {
+		ed := editor.New(strings.NewReader("if\r"), io.Discard)
+		ed.Continue = func(string) bool { return true }
+		if g, err := ed.ReadLine(true); err != nil {
+			t.Error("unexpected error:", err)
+		} else if e := "if\n"; g != e {
+			t.Errorf("expected %q, got %q", e, g)
+		}
+	})
+}
+
+func TestEdit(t *testing.T) {
+	for _, mode := range []editor.Mode{editor.Emacs, editor.Vi} {
+		keys := "foo\x18\x05"
+		if mode == editor.Vi {
+			keys = "foo\x1bv"
+		}
+		ed := editor.New(strings.NewReader(keys), io.Discard)
+		ed.Mode = mode
+		ed.Edit = func(s string) (string, error) {
+			return s + " bar\n", nil
+		}
+		if g, err := ed.ReadLine(false); err != nil {
+			t.Error("unexpected error:", err)
+		} else if e := "foo bar\n"; g != e {
+			t.Errorf("expected %q, got %q", e, g)
+		}
+
+		ed = editor.New(strings.NewReader(keys+"\r"), io.Discard)
+		ed.Mode = mode
+		ed.Edit = func(s string) (string, error) {
+			return "", errors.New("error")
+		}
+		if g, err := ed.ReadLine(false); err != nil {
+			t.Error("unexpected error:", err)
+		} else if e := "foo\n"; g != e {
+			t.Errorf("expected %q, got %q", e, g)
+		}
+	}
+}
+
+type history []string
+
+func (h history) Len() int        { return len(h) }
+func (h history) At(i int) string { return h[i] }
+
+var historyTests = []struct {
+	mode editor.Mode
+	keys string
+	line string
+}{
+	{editor.Emacs, "\x10\r", "echo baz\n"},
+	{editor.Emacs, "\x1b[A\x1b[A\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Emacs, "\x10\x10\x10\x10\x10\r", "echo foo\n"},
+	{editor.Emacs, "\x10\x10\x10\x10\x10\x10\r", "echo foo\n"},
+	{editor.Emacs, "abc\x10\x0e\r", "abc\n"},
+	{editor.Emacs, "\x10\x1b[B\x0e\r", "\n"},
+	{editor.Emacs, "\x12bar\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Emacs, "\x12echo\x12\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Emacs, "\x12echo\x12\x12\x12\r", "echo foo\n"},
+	{editor.Emacs, "\x12baz\x05 qux\r", "echo baz qux\n"},
+	{editor.Emacs, "\x12foo\x0b\r", "echo \n"},
+	{editor.Emacs, "\x12foo\x7f\x7f\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Emacs, "abc\x12echo\x07\r", "abc\n"},
+	{editor.Emacs, "\x12qqq\r", "\n"},
+	{editor.Vi, "\x1bk\r", "echo baz\n"},
+	{editor.Vi, "\x1b2k\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Vi, "\x1bkkk\r", "echo foo\n"},
+	{editor.Vi, "abc\x1bkj\r", "abc\n"},
+	{editor.Vi, "\x1bG\r", "echo foo\n"},
+	{editor.Vi, "\x1b2G\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Vi, "\x1b/foo\r\r", "echo foo\n"},
+	{editor.Vi, "\x1b/^echo\rn\r", "echo foo\n"},
+	{editor.Vi, "\x1b/echo\rnN\r", "echo baz\n"},
+	{editor.Vi, "\x1b/e*o bar\r\r", "if true; then\n\techo bar\nfi\n"},
+	{editor.Vi, "\x1b/xyz\r\r", "\n"},
+	{editor.Vi, "\x1b/\x7f\r", "\n"},
+	{editor.Vi, "\x1bkxU\r", "echo baz\n"},
+	{editor.Vi, "\x1b[A\r", "echo baz\n"},
+}
+
+func TestHistory(t *testing.T) {
+	for _, tt := range historyTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		ed.Mode = tt.mode
+		ed.History = history{"echo foo", "if true; then\n\techo bar\nfi", "echo baz"}
+		switch g, err := ed.ReadLine(false); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.keys, err)
+		case g != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, g)
+		}
+	}
+}
+
+func complete(text string, pos int) (int, []string) {
+	start := strings.LastIndexByte(text[:pos], ' ') + 1
+	var list []string
+	for _, s := range []string{"dir/a", "dir/b", "echo", "fob/", "foo", "foobar"} {
+		if strings.HasPrefix(s, text[start:pos]) {
+			list = append(list, s)
+		}
+	}
+	return start, list
+}
+
+var completeTests = []struct {
+	mode editor.Mode
+	keys string
+	line string
+}{
+	{editor.Emacs, "ec\t\r", "echo \n"},
+	{editor.Emacs, "ec\tfoo\r", "echo foo\n"},
+	{editor.Emacs, "echo fob\t\r", "echo fob/\n"},
+	{editor.Emacs, "echo foob\t\r", "echo foobar \n"},
+	{editor.Emacs, "echo fo\t\r", "echo fo\n"},
+	{editor.Emacs, "echo d\t\t\r", "echo dir/\n"},
+	{editor.Emacs, "echo x\t\r", "echo x\n"},
+	{editor.Emacs, "echo fo\x1b*\r", "echo fob/ foo foobar \n"},
+	{editor.Emacs, "echo fo\x1b=\r", "echo fo\n"},
+	{editor.Emacs, "ec\t\x1f\r", "ec\n"},
+	{editor.Emacs, "ec\x02\t\r", "echo c\n"},
+	{editor.Vi, "ec\t\r", "echo \n"},
+	{editor.Vi, "echo fo\t\r", "echo fo\n"},
+	{editor.Vi, "echo foob\x1b\\\r", "echo foobar \n"},
+	{editor.Vi, "echo foob\x1b\\x\r", "echo foobar x\n"},
+	{editor.Vi, "echo fob\x1b\\\r", "echo fob/\n"},
+	{editor.Vi, "echo foob\x1b\\\x1bu\r", "echo foob\n"},
+	{editor.Vi, "echo x\x1b\\\r", "echo x\n"},
+	{editor.Vi, "echo fo\x1b*\r", "echo fob/ foo foobar \n"},
+	{editor.Vi, "echo fo\x1b*x\r", "echo fob/ foo foobar x\n"},
+	{editor.Vi, "echo fo\x1b=x\r", "echo f\n"},
+	{editor.Vi, "ec foo\x1b0\\\r", "echo  foo\n"},
+}
+
+func TestComplete(t *testing.T) {
+	for _, tt := range completeTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		ed.Mode = tt.mode
+		ed.Complete = complete
+		switch g, err := ed.ReadLine(false); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.keys, err)
+		case g != tt.line:
+			t.Errorf("%q: expected %q, got %q", tt.keys, tt.line, g)
+		}
+	}
+}
+
+func TestCompleteList(t *testing.T) {
+	for keys, list := range map[string]string{
+		"echo fo\t\r":   "fob/    foo     foobar",
+		"echo dir/\t\r": "a  b",
+	} {
+		var b strings.Builder
+		ed := editor.New(strings.NewReader(keys), &b)
+		ed.Complete = complete
+		if _, err := ed.ReadLine(false); err != nil {
+			t.Fatal(err)
+		}
+		if g, e := b.String(), "\r\n"+list+"\r\n"; !strings.Contains(g, e) {
+			t.Errorf("%q: expected to contain %q, got %q", keys, e, g)
+		}
+	}
+}
+
+var errorTests = []struct {
+	mode editor.Mode
+	keys string
+	err  error
+}{
+	{editor.Emacs, "", io.EOF},
+	{editor.Emacs, "\x04", io.EOF},
+	{editor.Emacs, "foo\x03", editor.ErrInterrupted},
+	{editor.Vi, "", io.EOF},
+	{editor.Vi, "\x04", io.EOF},
+	{editor.Vi, "foo\x03", editor.ErrInterrupted},
+	{editor.Vi, "foo\x1b\x03", editor.ErrInterrupted},
+}
+
+func TestError(t *testing.T) {
+	for _, tt := range errorTests {
+		ed := editor.New(strings.NewReader(tt.keys), io.Discard)
+		ed.Mode = tt.mode
+		if _, err := ed.ReadLine(false); err != tt.err {
+			t.Errorf("%q: expected %v, got %v", tt.keys, tt.err, err)
+		}
+	}
+}
+
+var refreshTests = []struct {
+	prompt string
+	keys   string
+	out    string
+}{
+	{"$ ", "a\r", "\r\x1b[J$ \r\x1b[2C" + "\r\x1b[J$ a\r\x1b[3C" + "\r\x1b[J$ a\r\x1b[3C\r\n"},
+	{"$ ", "ab\x02\r", "\r\x1b[J$ \r\x1b[2C" + "\r\x1b[J$ a\r\x1b[3C" + "\r\x1b[J$ ab\r\x1b[4C" + "\r\x1b[J$ ab\r\x1b[3C" + "\r\x1b[J$ ab\r\x1b[4C\r\n"},
+	{"\x1b[1m$\x1b[m ", "\t\x01\r", "\r\x1b[J\x1b[1m$\x1b[m \r\x1b[2C" + "\r\x1b[J\x1b[1m$\x1b[m       \r\x1b[8C" + "\r\x1b[J\x1b[1m$\x1b[m       \r\x1b[2C" + "\r\x1b[J\x1b[1m$\x1b[m       \r\x1b[8C\r\n"},
+	{"\n$ ", "\x16\x01\r", "\r\x1b[J\r\n$ \r\x1b[2C" + "\x1b[1A\r\x1b[J\r\n$ ^A\r\x1b[4C" + "\x1b[1A\r\x1b[J\r\n$ ^A\r\x1b[4C\r\n"},
+	{"$ ", "あ\r", "\r\x1b[J$ \r\x1b[2C" + "\r\x1b[J$ あ\r\x1b[4C" + "\r\x1b[J$ あ\r\x1b[4C\r\n"},
+	{"$ ", "\x1b\rb\x10\r", "\r\x1b[J$ \r\x1b[2C" + "\r\x1b[J$ \r\n$ \r\x1b[2C" + "\x1b[1A\r\x1b[J$ \r\n$ b\r\x1b[3C" + "\x1b[1A\r\x1b[J$ \r\n$ b\x1b[1A\r\x1b[2C" + "\r\x1b[J$ \r\n$ b\r\x1b[3C\r\n"},
+	{strings.Repeat("-", 78), "ab\r", "\r\x1b[J" + strings.Repeat("-", 78) + "\r\x1b[78C" + "\r\x1b[J" + strings.Repeat("-", 78) + "a\r\x1b[79C" + "\r\x1b[J" + strings.Repeat("-", 78) + "ab\r\n\r" + "\x1b[1A\r\x1b[J" + strings.Repeat("-", 78) + "ab\r\n\r\r\n"},
+}
+
+func TestRefresh(t *testing.T) {
+	for _, tt := range refreshTests {
+		var b strings.Builder
+		ed := editor.New(strings.NewReader(tt.keys), &b)
+		ed.Prompt = func(bool) string { return tt.prompt }
+		if _, err := ed.ReadLine(false); err != nil {
+			t.Fatal(err)
+		}
+		if g, e := b.String(), tt.out; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.keys, e, g)
+		}
+	}
+}
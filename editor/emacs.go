@@ -0,0 +1,204 @@
+//
+// go.sh/editor :: emacs.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"io"
+	"unicode"
+)
+
+// emacs applies the key k in the emacs editing mode. It reports whether
+// the edit line is accepted.
+func (ed *Editor) emacs(k key) (bool, error) {
+	if k == esc {
+		// the next key is typed with the meta key
+		n, err := ed.readKey()
+		if err != nil {
+			return false, err
+		}
+		k = meta | n
+	}
+	if !isPrint(k) {
+		ed.typing = false
+	}
+
+	switch k {
+	case ctrl('M'), ctrl('J'):
+		return !ed.newline(), nil
+	case meta | ctrl('M'), meta | ctrl('J'):
+		ed.save()
+		ed.insertRunes('\n')
+	case ctrl('C'):
+		return false, ErrInterrupted
+	case ctrl('D'):
+		switch {
+		case len(ed.buf) == 0:
+			return false, io.EOF
+		case ed.pos == len(ed.buf):
+			return false, errContinue
+		}
+		ed.save()
+		ed.delete(ed.pos, ed.pos+1, false)
+	case ctrl('A'), keyHome:
+		ed.pos = ed.bol(ed.pos)
+	case ctrl('E'), keyEnd:
+		ed.pos = ed.eol(ed.pos)
+	case ctrl('B'), keyLeft:
+		if ed.pos == 0 {
+			return false, errContinue
+		}
+		ed.pos--
+	case ctrl('F'), keyRight:
+		if ed.pos == len(ed.buf) {
+			return false, errContinue
+		}
+		ed.pos++
+	case meta | 'b':
+		ed.pos = ed.backwardWord(ed.pos)
+	case meta | 'f':
+		ed.pos = ed.forwardWord(ed.pos)
+	case ctrl('P'), keyUp:
+		if !ed.lineUp() {
+			return false, ed.recall(ed.hist - 1)
+		}
+	case ctrl('N'), keyDown:
+		if !ed.lineDown() {
+			return false, ed.recall(ed.hist + 1)
+		}
+	case ctrl('R'):
+		return ed.isearch()
+	case meta | '*':
+		return false, ed.completeAll()
+	case meta | '=':
+		return false, ed.listCandidates()
+	case backspace, ctrl('H'):
+		if ed.pos == 0 {
+			return false, errContinue
+		}
+		ed.save()
+		ed.delete(ed.pos-1, ed.pos, false)
+	case keyDelete:
+		if ed.pos == len(ed.buf) {
+			return false, errContinue
+		}
+		ed.save()
+		ed.delete(ed.pos, ed.pos+1, false)
+	case ctrl('K'):
+		eol := ed.eol(ed.pos)
+		switch {
+		case ed.pos == len(ed.buf):
+			return false, errContinue
+		case eol == ed.pos:
+			// kill the <newline>
+			eol++
+		}
+		ed.save()
+		ed.delete(ed.pos, eol, true)
+	case ctrl('U'):
+		ed.save()
+		ed.delete(ed.bol(ed.pos), ed.pos, true)
+	case ctrl('W'):
+		i := ed.pos
+		for i > 0 && isBlank(ed.buf[i-1]) {
+			i--
+		}
+		for i > 0 && !isBlank(ed.buf[i-1]) {
+			i--
+		}
+		ed.save()
+		ed.delete(i, ed.pos, true)
+	case meta | 'd':
+		ed.save()
+		ed.delete(ed.pos, ed.forwardWord(ed.pos), true)
+	case meta | backspace, meta | ctrl('H'):
+		ed.save()
+		ed.delete(ed.backwardWord(ed.pos), ed.pos, true)
+	case ctrl('Y'):
+		if len(ed.kill) == 0 {
+			return false, errContinue
+		}
+		ed.save()
+		ed.insertRunes(ed.kill...)
+	case ctrl('T'):
+		i := ed.pos
+		if i == ed.eol(i) {
+			i--
+		}
+		if i <= ed.bol(ed.pos) {
+			return false, errContinue
+		}
+		ed.save()
+		ed.buf[i-1], ed.buf[i] = ed.buf[i], ed.buf[i-1]
+		ed.pos = i + 1
+	case ctrl('_'):
+		return false, ed.revert()
+	case ctrl('L'):
+		ed.clear()
+	case ctrl('V'):
+		r, _, err := ed.r.ReadRune()
+		if err != nil {
+			return false, err
+		}
+		ed.save()
+		ed.insertRunes(r)
+	case ctrl('X'):
+		n, err := ed.readKey()
+		switch {
+		case err != nil:
+			return false, err
+		case n == ctrl('E'):
+			return ed.edit()
+		}
+		return false, errContinue
+	case ctrl('I'):
+		if ed.Complete != nil {
+			ed.typing = false
+			return false, ed.complete()
+		}
+		fallthrough
+	default:
+		if !isPrint(k) {
+			return false, errContinue
+		}
+		if !ed.typing {
+			// consecutive insertions are undone at once
+			ed.save()
+			ed.typing = true
+		}
+		ed.insertRunes(rune(k))
+	}
+	return false, nil
+}
+
+// forwardWord returns the index of the end of the next word.
+func (ed *Editor) forwardWord(i int) int {
+	for i < len(ed.buf) && !isWordRune(ed.buf[i]) {
+		i++
+	}
+	for i < len(ed.buf) && isWordRune(ed.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// backwardWord returns the index of the beginning of the previous word.
+func (ed *Editor) backwardWord(i int) int {
+	for i > 0 && !isWordRune(ed.buf[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(ed.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// isWordRune reports whether r is a constituent of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
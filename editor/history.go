@@ -0,0 +1,229 @@
+//
+// go.sh/editor :: history.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hattya/go.sh/pattern"
+)
+
+// History is the interface which provides the commands recalled by the
+// Editor. The commands are indexed from the oldest one.
+type History interface {
+	// Len returns the number of the commands.
+	Len() int
+
+	// At returns the i-th command.
+	At(i int) string
+}
+
+// histLen returns the number of the commands in the history.
+func (ed *Editor) histLen() int {
+	if ed.History == nil {
+		return 0
+	}
+	return ed.History.Len()
+}
+
+// recall replaces the edit line with the i-th command in the history, or
+// with the line which was being edited if i is the length of the history.
+func (ed *Editor) recall(i int) error {
+	n := ed.histLen()
+	switch {
+	case i < 0 || i > n:
+		return errContinue
+	case i == ed.hist:
+		return nil
+	case ed.hist == n:
+		ed.line = slices.Clone(ed.buf)
+	}
+	ed.hist = i
+	if i < n {
+		ed.buf = append(ed.buf[:0], []rune(ed.History.At(i))...)
+	} else {
+		ed.buf = append(ed.buf[:0], ed.line...)
+	}
+	ed.pos = len(ed.buf)
+	ed.undo = ed.undo[:0]
+	ed.typing = false
+	if ed.Mode == Vi {
+		if !ed.insert {
+			ed.pos = 0
+		}
+		// for U
+		ed.save()
+	}
+	return nil
+}
+
+// isearch searches the history backward incrementally for the typed
+// string. The key which terminates the search is applied to the found
+// command.
+func (ed *Editor) isearch() (bool, error) {
+	if ed.histLen() == 0 {
+		return false, errContinue
+	}
+	ps1, hist, pos := ed.ps1, ed.hist, ed.pos
+	line := slices.Clone(ed.buf)
+	defer func() { ed.ps1 = ps1 }()
+
+	var s []rune
+	i := hist
+	for {
+		ed.ps1 = "(reverse-i-search)`" + string(s) + "': "
+		ed.refresh()
+		k, err := ed.readKey()
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case k == ctrl('R'):
+			if len(s) == 0 || !ed.isearchFrom(s, i-1) {
+				ed.bell()
+				continue
+			}
+		case k == backspace || k == ctrl('H'):
+			if len(s) == 0 {
+				ed.bell()
+				continue
+			}
+			s = s[:len(s)-1]
+			i = hist
+			if len(s) > 0 {
+				ed.isearchFrom(s, hist)
+			}
+		case k == ctrl('G'):
+			ed.recall(hist)
+			ed.buf = append(ed.buf[:0], line...)
+			ed.pos = pos
+			return false, nil
+		case k == esc:
+			return false, nil
+		case k != ctrl('I') && isPrint(k):
+			s = append(s, rune(k))
+			if !ed.isearchFrom(s, i) {
+				s = s[:len(s)-1]
+				ed.bell()
+				continue
+			}
+		default:
+			ed.ps1 = ps1
+			return ed.emacs(k)
+		}
+		i = ed.hist
+	}
+}
+
+// isearchFrom searches the history backward from the i-th command for s,
+// and recalls the found command. The cursor is moved to s.
+func (ed *Editor) isearchFrom(s []rune, i int) bool {
+	if i == ed.hist {
+		// the current command
+		if j := strings.Index(string(ed.buf), string(s)); j >= 0 {
+			ed.pos = utf8.RuneCountInString(string(ed.buf)[:j])
+			return true
+		}
+		i--
+	}
+	for i = min(i, ed.histLen()-1); i >= 0; i-- {
+		c := ed.History.At(i)
+		if j := strings.Index(c, string(s)); j >= 0 {
+			ed.recall(i)
+			ed.pos = utf8.RuneCountInString(c[:j])
+			return true
+		}
+	}
+	return false
+}
+
+// viSearch searches the history for the pattern which is read after the
+// key k. "/" searches backward, and "?" searches forward. "n" and "N"
+// repeat the last search in the same and the opposite direction.
+func (ed *Editor) viSearch(k key) error {
+	if k == '/' || k == '?' {
+		s, ok, err := ed.readPattern(string(rune(k)))
+		switch {
+		case err != nil:
+			return err
+		case !ok:
+			return nil
+		case s != "":
+			ed.search = s
+		}
+		ed.backward = k == '/'
+	}
+	if ed.search == "" {
+		return errContinue
+	}
+
+	step := 1
+	if ed.backward == (k != 'N') {
+		step = -1
+	}
+	for i := ed.hist + step; 0 <= i && i < ed.histLen(); i += step {
+		if matchPattern(ed.search, ed.History.At(i)) {
+			return ed.recall(i)
+		}
+	}
+	return errContinue
+}
+
+// readPattern reads a search pattern on the edit line which is prefixed
+// by p. It reports false if the reading is canceled.
+func (ed *Editor) readPattern(p string) (string, bool, error) {
+	ps1, buf, pos := ed.ps1, ed.buf, ed.pos
+	defer func() { ed.ps1, ed.buf, ed.pos = ps1, buf, pos }()
+
+	ed.ps1 = p
+	ed.buf = nil
+	ed.pos = 0
+	for {
+		ed.refresh()
+		k, err := ed.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch k {
+		case ctrl('M'), ctrl('J'):
+			return string(ed.buf), true, nil
+		case esc, ctrl('C'):
+			return "", false, nil
+		case backspace, ctrl('H'):
+			if len(ed.buf) == 0 {
+				return "", false, nil
+			}
+			ed.delete(ed.pos-1, ed.pos, false)
+		default:
+			if !isPrint(k) {
+				ed.bell()
+				continue
+			}
+			ed.insertRunes(rune(k))
+		}
+	}
+}
+
+// matchPattern reports whether any line of s contains the pattern. If
+// the pattern begins with "^", it matches only the beginning of a line.
+func matchPattern(pat, s string) bool {
+	if p, ok := strings.CutPrefix(pat, "^"); ok {
+		pat = p
+	} else {
+		pat = "*" + pat
+	}
+	for l := range strings.SplitSeq(s, "\n") {
+		if _, err := pattern.Match([]string{pat}, pattern.Smallest|pattern.Prefix, l); err == nil {
+			return true
+		}
+	}
+	return false
+}
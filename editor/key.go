@@ -0,0 +1,119 @@
+//
+// go.sh/editor :: key.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+// key represents a key which is typed on the terminal.
+type key rune
+
+const (
+	keyUp key = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// meta is the bit of a key which is typed with the meta key, or after
+// the <ESC> key.
+const meta key = 1 << 24
+
+const (
+	esc       key = 0x1b
+	backspace key = 0x7f
+)
+
+// ctrl returns the control character for c.
+func ctrl(c rune) key {
+	return key(c & 0x1f)
+}
+
+// readKey reads a key from the terminal. An escape sequence is decoded
+// if it follows the <ESC> immediately.
+func (ed *Editor) readKey() (key, error) {
+	r, _, err := ed.r.ReadRune()
+	switch {
+	case err != nil:
+		return 0, err
+	case key(r) != esc || ed.r.Buffered() == 0:
+		return key(r), nil
+	}
+
+	r, _, err = ed.r.ReadRune()
+	switch {
+	case err != nil:
+		return esc, nil
+	case r == '[':
+		return ed.readCSI()
+	case r == 'O':
+		// SS3
+		r, _, err = ed.r.ReadRune()
+		if err != nil {
+			return keyUnknown, nil
+		}
+		return finalKey(r), nil
+	}
+	return meta | key(r), nil
+}
+
+// readCSI reads the control sequence which follows the CSI.
+func (ed *Editor) readCSI() (key, error) {
+	var n int
+	for {
+		r, _, err := ed.r.ReadRune()
+		switch {
+		case err != nil:
+			return keyUnknown, nil
+		case '0' <= r && r <= '9':
+			n = n*10 + int(r-'0')
+		case 0x20 <= r && r <= 0x3f:
+			// parameter and intermediate bytes
+		case r == '~':
+			switch n {
+			case 1, 7:
+				return keyHome, nil
+			case 3:
+				return keyDelete, nil
+			case 4, 8:
+				return keyEnd, nil
+			}
+			return keyUnknown, nil
+		case 0x40 <= r && r <= 0x7e:
+			return finalKey(r), nil
+		default:
+			return keyUnknown, nil
+		}
+	}
+}
+
+// finalKey returns the key for the final byte of an escape sequence.
+func finalKey(r rune) key {
+	switch r {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	}
+	return keyUnknown
+}
+
+// isPrint reports whether k inserts itself into the edit line.
+func isPrint(k key) bool {
+	return k == ctrl('I') || (0x20 <= k && k < meta && k != backspace)
+}
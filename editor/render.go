@@ -0,0 +1,225 @@
+//
+// go.sh/editor :: render.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// refresh redraws the prompts and the edit line, and moves the cursor.
+func (ed *Editor) refresh() {
+	s := &screen{width: ed.termWidth()}
+	// move to the first row of the edit line
+	if ed.row > 0 {
+		s.csi(ed.row, 'A')
+	}
+	s.b.WriteString("\r\x1b[J")
+
+	s.prompt(ed.ps1)
+	row, col := -1, 0
+	for i, r := range ed.buf {
+		if i == ed.pos {
+			row, col = s.cursor(r)
+		}
+		if r == '\n' {
+			s.newline()
+			s.prompt(ed.prompt2())
+		} else {
+			s.put(r)
+		}
+	}
+	if s.col >= s.width {
+		// the cursor is placed at the last column
+		s.newline()
+	}
+	if row < 0 {
+		row, col = s.row, s.col
+	}
+
+	if n := s.row - row; n > 0 {
+		s.csi(n, 'A')
+	}
+	s.b.WriteByte('\r')
+	if col > 0 {
+		s.csi(col, 'C')
+	}
+	ed.row = row
+	io.WriteString(ed.out, s.b.String())
+}
+
+// clear clears the screen.
+func (ed *Editor) clear() {
+	io.WriteString(ed.out, "\x1b[H\x1b[2J")
+	ed.row = 0
+}
+
+// termWidth returns the width of the terminal.
+func (ed *Editor) termWidth() int {
+	for _, v := range []any{ed.out, ed.in} {
+		if f, ok := v.(*os.File); ok {
+			if w := termWidth(f); w > 0 {
+				return w
+			}
+		}
+	}
+	return 80
+}
+
+// screen keeps track of the position of the cursor on the terminal while
+// the edit line is drawn.
+type screen struct {
+	b        strings.Builder
+	width    int
+	row, col int
+}
+
+// csi writes a control sequence which has the parameter n.
+func (s *screen) csi(n int, final byte) {
+	s.b.WriteString("\x1b[")
+	s.b.WriteString(strconv.Itoa(n))
+	s.b.WriteByte(final)
+}
+
+// cursor returns the position of the rune r when it is drawn.
+func (s *screen) cursor(r rune) (int, int) {
+	switch {
+	case s.col >= s.width:
+		if r == '\n' {
+			return s.row, s.width - 1
+		}
+		return s.row + 1, 0
+	case r != '\n' && r != '\t' && s.col+runeWidth(r, s.col) > s.width:
+		return s.row + 1, 0
+	}
+	return s.row, s.col
+}
+
+// newline moves the cursor to the beginning of the next row.
+func (s *screen) newline() {
+	s.b.WriteString("\r\n")
+	s.row++
+	s.col = 0
+}
+
+// prompt writes the prompt. Escape sequences are written as is, and
+// assumed to occupy no columns.
+func (s *screen) prompt(p string) {
+	for i := 0; i < len(p); {
+		switch {
+		case p[i] == '\n':
+			s.newline()
+			i++
+		case p[i] == 0x1b:
+			n := escLen(p[i:])
+			s.b.WriteString(p[i : i+n])
+			i += n
+		default:
+			r, n := utf8.DecodeRuneInString(p[i:])
+			s.put(r)
+			i += n
+		}
+	}
+}
+
+// put writes the rune r. A <tab> is expanded to <space>s, and the other
+// control characters are written in caret notation.
+func (s *screen) put(r rune) {
+	w := runeWidth(r, s.col)
+	if s.col >= s.width || (r != '\t' && s.col+w > s.width) {
+		// the terminal wraps the line automatically
+		s.row++
+		s.col = 0
+		w = runeWidth(r, s.col)
+	}
+	switch {
+	case r == '\t':
+		w = min(w, s.width-s.col)
+		s.b.WriteString(strings.Repeat(" ", w))
+	case r < 0x20:
+		s.b.WriteByte('^')
+		s.b.WriteByte(byte(r) + '@')
+	case r == 0x7f:
+		s.b.WriteString("^?")
+	default:
+		s.b.WriteRune(r)
+	}
+	s.col += w
+}
+
+// escLen returns the length of the escape sequence at the beginning of
+// s.
+func escLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		// CSI
+		for i := 2; i < len(s); i++ {
+			if 0x40 <= s[i] && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		// OSC terminated by BEL or ST
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1
+			case s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\':
+				return i + 2
+			}
+		}
+	default:
+		return 2
+	}
+	return len(s)
+}
+
+// runeWidth returns the number of columns occupied by r when it is drawn
+// at the column col.
+func runeWidth(r rune, col int) int {
+	switch {
+	case r == '\t':
+		return 8 - col%8
+	case r < 0x20 || r == 0x7f:
+		return 2
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// isWide reports whether r is an East Asian wide or fullwidth character.
+func isWide(r rune) bool {
+	switch {
+	case r < 0x1100:
+		return false
+	case 0x1100 <= r && r <= 0x115f, // Hangul Jamo
+		0x2e80 <= r && r <= 0x303e,   // CJK Radicals .. CJK Symbols and Punctuation
+		0x3041 <= r && r <= 0x33ff,   // Hiragana .. CJK Compatibility
+		0x3400 <= r && r <= 0x4dbf,   // CJK Unified Ideographs Extension A
+		0x4e00 <= r && r <= 0x9fff,   // CJK Unified Ideographs
+		0xa000 <= r && r <= 0xa4cf,   // Yi Syllables .. Yi Radicals
+		0xac00 <= r && r <= 0xd7a3,   // Hangul Syllables
+		0xf900 <= r && r <= 0xfaff,   // CJK Compatibility Ideographs
+		0xfe30 <= r && r <= 0xfe4f,   // CJK Compatibility Forms
+		0xff00 <= r && r <= 0xff60,   // Fullwidth Forms
+		0xffe0 <= r && r <= 0xffe6,   // Fullwidth Signs
+		0x1f300 <= r && r <= 0x1f64f, // Miscellaneous Symbols and Pictographs .. Emoticons
+		0x1f900 <= r && r <= 0x1f9ff, // Supplemental Symbols and Pictographs
+		0x20000 <= r && r <= 0x3fffd: // CJK Unified Ideographs Extension B ..
+		return true
+	}
+	return false
+}
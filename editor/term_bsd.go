@@ -0,0 +1,18 @@
+//
+// go.sh/editor :: term_bsd.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package editor
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
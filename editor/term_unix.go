@@ -0,0 +1,80 @@
+//
+// go.sh/editor :: term_unix.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+//go:build unix
+
+package editor
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal reports whether v is a terminal.
+func IsTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+	var t syscall.Termios
+	return ioctl(f, ioctlGetTermios, unsafe.Pointer(&t)) == nil
+}
+
+// makeRaw puts the terminal f into raw mode, and returns the function to
+// restore it.
+func makeRaw(f *os.File, _ io.Writer) (func(), error) {
+	var t syscall.Termios
+	if err := ioctl(f, ioctlGetTermios, unsafe.Pointer(&t)); err != nil {
+		return nil, err
+	}
+	saved := t
+	t.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err := ioctl(f, ioctlSetTermios, unsafe.Pointer(&t)); err != nil {
+		return nil, err
+	}
+	return func() { ioctl(f, ioctlSetTermios, unsafe.Pointer(&saved)) }, nil
+}
+
+// winsize represents the size of a terminal.
+type winsize struct {
+	row, col       uint16
+	xpixel, ypixel uint16
+}
+
+// termWidth returns the width of the terminal f, or 0 if it is unknown.
+func termWidth(f *os.File) int {
+	var ws winsize
+	if err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0
+	}
+	return int(ws.col)
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
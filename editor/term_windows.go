@@ -0,0 +1,100 @@
+//
+// go.sh/editor :: term_windows.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+)
+
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+// IsTerminal reports whether v is a terminal.
+func IsTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// makeRaw puts the console f into raw mode, and enables the processing
+// of escape sequences for out. It returns the function to restore them.
+func makeRaw(f *os.File, out io.Writer) (func(), error) {
+	in := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(in, &mode); err != nil {
+		return nil, err
+	}
+	raw := mode&^(enableEchoInput|enableLineInput|enableProcessedInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(in, raw); err != nil {
+		return nil, err
+	}
+	restore := func() { setConsoleMode(in, mode) }
+
+	if f, ok := out.(*os.File); ok {
+		out := syscall.Handle(f.Fd())
+		var mode uint32
+		if syscall.GetConsoleMode(out, &mode) == nil && setConsoleMode(out, mode|enableVirtualTerminalProcessing) == nil {
+			restoreIn := restore
+			restore = func() {
+				setConsoleMode(out, mode)
+				restoreIn()
+			}
+		}
+	}
+	return restore, nil
+}
+
+func setConsoleMode(h syscall.Handle, mode uint32) error {
+	if r, _, err := procSetConsoleMode.Call(uintptr(h), uintptr(mode)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+type coord struct {
+	x, y int16
+}
+
+type smallRect struct {
+	left, top, right, bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	size              coord
+	cursorPosition    coord
+	attributes        uint16
+	window            smallRect
+	maximumWindowSize coord
+}
+
+// termWidth returns the width of the console f, or 0 if it is unknown.
+func termWidth(f *os.File) int {
+	var info consoleScreenBufferInfo
+	if r, _, _ := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0
+	}
+	return int(info.window.right - info.window.left + 1)
+}
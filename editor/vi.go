@@ -0,0 +1,553 @@
+//
+// go.sh/editor :: vi.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package editor
+
+import (
+	"io"
+	"slices"
+	"unicode"
+)
+
+// vi applies the key k in the vi editing mode. It reports whether the
+// edit line is accepted.
+func (ed *Editor) vi(k key) (done bool, err error) {
+	if k > 0 && k&meta != 0 {
+		// <ESC> followed by a command
+		if ed.insert {
+			ed.viEscape()
+		}
+		k &^= meta
+	}
+	if ed.insert {
+		return ed.viInsert(k)
+	}
+
+	done, err = ed.viCommand(k)
+	if !ed.insert && ed.pos > ed.bol(ed.pos) && ed.pos == ed.eol(ed.pos) {
+		// the cursor is placed on a character in command mode
+		ed.pos--
+	}
+	return
+}
+
+// viInsert applies the key k in insert mode.
+func (ed *Editor) viInsert(k key) (bool, error) {
+	switch k {
+	case esc:
+		ed.viEscape()
+	case ctrl('M'), ctrl('J'):
+		return !ed.newline(), nil
+	case ctrl('C'):
+		return false, ErrInterrupted
+	case ctrl('D'):
+		if len(ed.buf) == 0 {
+			return false, io.EOF
+		}
+		return false, errContinue
+	case backspace, ctrl('H'):
+		if ed.pos == 0 {
+			return false, errContinue
+		}
+		ed.delete(ed.pos-1, ed.pos, false)
+	case ctrl('W'):
+		ed.delete(ed.prevWord(ed.pos, false), ed.pos, false)
+	case ctrl('U'):
+		ed.delete(ed.bol(ed.pos), ed.pos, true)
+	case ctrl('V'):
+		r, _, err := ed.r.ReadRune()
+		if err != nil {
+			return false, err
+		}
+		ed.insertRunes(r)
+	case keyLeft:
+		if ed.pos == 0 {
+			return false, errContinue
+		}
+		ed.pos--
+	case keyRight:
+		if ed.pos == len(ed.buf) {
+			return false, errContinue
+		}
+		ed.pos++
+	case keyHome:
+		ed.pos = ed.bol(ed.pos)
+	case keyEnd:
+		ed.pos = ed.eol(ed.pos)
+	case keyUp:
+		if !ed.lineUp() {
+			return false, ed.recall(ed.hist - 1)
+		}
+	case keyDown:
+		if !ed.lineDown() {
+			return false, ed.recall(ed.hist + 1)
+		}
+	case keyDelete:
+		if ed.pos == len(ed.buf) {
+			return false, errContinue
+		}
+		ed.delete(ed.pos, ed.pos+1, false)
+	case ctrl('I'):
+		if ed.Complete != nil {
+			return false, ed.complete()
+		}
+		fallthrough
+	default:
+		switch {
+		case !isPrint(k):
+			return false, errContinue
+		case ed.replace:
+			ed.overwrite(rune(k))
+		default:
+			ed.insertRunes(rune(k))
+		}
+	}
+	return false, nil
+}
+
+// viEscape switches to command mode.
+func (ed *Editor) viEscape() {
+	ed.insert = false
+	ed.replace = false
+	if ed.pos > ed.bol(ed.pos) {
+		ed.pos--
+	}
+}
+
+// viInsertMode switches to insert mode. If replace is true, the typed
+// characters replace the existing ones.
+func (ed *Editor) viInsertMode(replace bool) {
+	ed.insert = true
+	ed.replace = replace
+}
+
+// viCommand applies the key k in command mode.
+func (ed *Editor) viCommand(k key) (bool, error) {
+	count, k, err := ed.viCount(k)
+	if err != nil {
+		return false, err
+	}
+	bol, eol := ed.bol(ed.pos), ed.eol(ed.pos)
+
+	switch k {
+	case ctrl('M'), ctrl('J'):
+		if ed.newline() {
+			ed.viInsertMode(false)
+			return false, nil
+		}
+		return true, nil
+	case ctrl('C'):
+		return false, ErrInterrupted
+	case ctrl('L'):
+		ed.clear()
+	case 'i':
+		ed.save()
+		ed.viInsertMode(false)
+	case 'a':
+		ed.save()
+		if ed.pos < eol {
+			ed.pos++
+		}
+		ed.viInsertMode(false)
+	case 'I':
+		ed.save()
+		ed.pos = ed.firstNonBlank(bol)
+		ed.viInsertMode(false)
+	case 'A':
+		ed.save()
+		ed.pos = eol
+		ed.viInsertMode(false)
+	case 'R':
+		ed.save()
+		ed.viInsertMode(true)
+	case 'r':
+		r, _, err := ed.r.ReadRune()
+		switch {
+		case err != nil:
+			return false, err
+		case ed.pos+count > eol:
+			return false, errContinue
+		}
+		ed.save()
+		for i := range count {
+			ed.buf[ed.pos+i] = r
+		}
+		ed.pos += count - 1
+	case 'x':
+		if ed.pos == eol {
+			return false, errContinue
+		}
+		ed.save()
+		ed.delete(ed.pos, min(ed.pos+count, eol), true)
+	case 'X':
+		if ed.pos == bol {
+			return false, errContinue
+		}
+		ed.save()
+		ed.delete(max(ed.pos-count, bol), ed.pos, true)
+	case '~':
+		if ed.pos == eol {
+			return false, errContinue
+		}
+		ed.save()
+		for ; count > 0 && ed.pos < eol; count-- {
+			r := ed.buf[ed.pos]
+			if unicode.IsUpper(r) {
+				ed.buf[ed.pos] = unicode.ToLower(r)
+			} else {
+				ed.buf[ed.pos] = unicode.ToUpper(r)
+			}
+			ed.pos++
+		}
+	case 'D', 'C':
+		ed.save()
+		ed.delete(ed.pos, eol, true)
+		if k == 'C' {
+			ed.viInsertMode(false)
+		}
+	case 'S':
+		ed.save()
+		ed.delete(bol, eol, true)
+		ed.viInsertMode(false)
+	case 'd', 'c', 'y':
+		return false, ed.viOperator(k, count)
+	case 'p', 'P':
+		if len(ed.kill) == 0 {
+			return false, errContinue
+		}
+		ed.save()
+		if k == 'p' && ed.pos < eol {
+			ed.pos++
+		}
+		for range count {
+			ed.insertRunes(ed.kill...)
+		}
+		ed.pos--
+	case 'u':
+		return false, ed.revert()
+	case 'U':
+		if len(ed.undo) == 0 {
+			return false, errContinue
+		}
+		s := ed.undo[0]
+		ed.save()
+		ed.buf = append(ed.buf[:0], s.buf...)
+		ed.pos = min(s.pos, len(ed.buf))
+	case 'k', '-', keyUp:
+		for ; count > 0 && ed.lineUp(); count-- {
+		}
+		if count > 0 {
+			return false, ed.recall(ed.hist - count)
+		}
+	case 'j', '+', keyDown:
+		for ; count > 0 && ed.lineDown(); count-- {
+		}
+		if count > 0 {
+			return false, ed.recall(ed.hist + count)
+		}
+	case 'G':
+		return false, ed.recall(count - 1)
+	case '/', '?', 'n', 'N':
+		return false, ed.viSearch(k)
+	case '#':
+		ed.save()
+		for i := len(ed.buf); i >= 0; i-- {
+			if i == 0 || ed.buf[i-1] == '\n' {
+				ed.buf = slices.Insert(ed.buf, i, '#')
+			}
+		}
+		return true, nil
+	case 'v':
+		return ed.edit()
+	case '=', '\\', '*':
+		// move to the end of the current bigword
+		pos := ed.pos
+		for ed.pos < eol && !isBlank(ed.buf[ed.pos]) {
+			ed.pos++
+		}
+		if ed.pos == pos && ed.pos < eol {
+			ed.pos++
+		}
+		switch k {
+		case '=':
+			err = ed.listCandidates()
+			ed.pos = pos
+			return false, err
+		case '\\':
+			err = ed.complete()
+		case '*':
+			err = ed.completeAll()
+		}
+		if err != nil {
+			ed.pos = pos
+			return false, err
+		}
+		ed.viInsertMode(false)
+	default:
+		i, _, ok, err := ed.viMotion(k, count)
+		switch {
+		case err != nil:
+			return false, err
+		case !ok:
+			return false, errContinue
+		}
+		ed.pos = i
+	}
+	return false, nil
+}
+
+// viCount reads the count which starts with the key k. It returns 1 if
+// the count is not specified.
+func (ed *Editor) viCount(k key) (int, key, error) {
+	var n int
+	for ('1' <= k && k <= '9') || (n > 0 && k == '0') {
+		n = n*10 + int(k-'0')
+		var err error
+		if k, err = ed.readKey(); err != nil {
+			return 0, 0, err
+		}
+	}
+	return max(n, 1), k, nil
+}
+
+// viOperator applies the operator op to the range between the cursor and
+// the destination of the motion which is read next.
+func (ed *Editor) viOperator(op key, count int) error {
+	k, err := ed.readKey()
+	if err != nil {
+		return err
+	}
+	n, k, err := ed.viCount(k)
+	if err != nil {
+		return err
+	}
+	count *= n
+
+	var i, j int
+	if k == op {
+		// the current line
+		i, j = ed.bol(ed.pos), ed.eol(ed.pos)
+		if op == 'd' {
+			switch {
+			case j < len(ed.buf):
+				j++
+			case i > 0:
+				i--
+			}
+		}
+	} else {
+		if op == 'c' && ed.pos < len(ed.buf) && !isBlank(ed.buf[ed.pos]) {
+			// cw is like ce
+			switch k {
+			case 'w':
+				k = 'e'
+			case 'W':
+				k = 'E'
+			}
+		}
+		dst, inclusive, ok, err := ed.viMotion(k, count)
+		switch {
+		case err != nil:
+			return err
+		case !ok:
+			return errContinue
+		case (k == 'w' || k == 'W') && dst > ed.eol(ed.pos):
+			dst = ed.eol(ed.pos)
+		}
+		i, j = min(ed.pos, dst), max(ed.pos, dst)
+		if inclusive && j < len(ed.buf) {
+			j++
+		}
+	}
+
+	switch op {
+	case 'y':
+		ed.kill = slices.Clone(ed.buf[i:j])
+		ed.pos = min(ed.pos, i)
+	default:
+		ed.save()
+		ed.delete(i, j, true)
+		if op == 'c' {
+			ed.viInsertMode(false)
+		}
+	}
+	return nil
+}
+
+// viMotion returns the destination of the motion k. It reports whether
+// the destination is included in the range of an operator, and whether k
+// is a motion.
+func (ed *Editor) viMotion(k key, count int) (i int, inclusive, ok bool, err error) {
+	bol, eol := ed.bol(ed.pos), ed.eol(ed.pos)
+	i = ed.pos
+	switch k {
+	case 'h', keyLeft, backspace, ctrl('H'):
+		i = max(i-count, bol)
+		ok = i < ed.pos
+	case 'l', ' ', keyRight:
+		i = min(i+count, eol)
+		ok = i > ed.pos
+	case '0', keyHome:
+		i, ok = bol, true
+	case '^':
+		i, ok = ed.firstNonBlank(bol), true
+	case '$', keyEnd:
+		i, ok = eol, true
+	case '|':
+		i, ok = bol+min(count-1, eol-bol), true
+	case 'w', 'W':
+		for range count {
+			i = ed.nextWord(i, k == 'W')
+		}
+		ok = i > ed.pos
+	case 'b', 'B':
+		for range count {
+			i = ed.prevWord(i, k == 'B')
+		}
+		ok = i < ed.pos
+	case 'e', 'E':
+		for range count {
+			i = ed.endWord(i, k == 'E')
+		}
+		inclusive, ok = true, i > ed.pos
+	case 'f', 'F', 't', 'T':
+		r, _, err := ed.r.ReadRune()
+		if err != nil {
+			return 0, false, false, err
+		}
+		ed.find = [2]rune{rune(k), r}
+		i, inclusive, ok = ed.viFind(ed.find[0], ed.find[1], count)
+	case ';', ',':
+		if ed.find[0] == 0 {
+			return
+		}
+		op := ed.find[0]
+		if k == ',' {
+			// reverse the direction
+			switch op {
+			case 'f', 't':
+				op -= 'a' - 'A'
+			default:
+				op += 'a' - 'A'
+			}
+		}
+		i, inclusive, ok = ed.viFind(op, ed.find[1], count)
+	}
+	return
+}
+
+// viFind returns the destination of the motion op which finds the count-th
+// occurrence of r in the current line.
+func (ed *Editor) viFind(op, r rune, count int) (i int, inclusive, ok bool) {
+	bol, eol := ed.bol(ed.pos), ed.eol(ed.pos)
+	i = ed.pos
+	for range count {
+		switch op {
+		case 'f', 't':
+			j := i + 1
+			if op == 't' {
+				j++
+			}
+			for j < eol && ed.buf[j] != r {
+				j++
+			}
+			if j >= eol {
+				return ed.pos, false, false
+			}
+			i = j
+			if op == 't' {
+				i--
+			}
+		default:
+			j := i - 1
+			if op == 'T' {
+				j--
+			}
+			for j >= bol && ed.buf[j] != r {
+				j--
+			}
+			if j < bol {
+				return ed.pos, false, false
+			}
+			i = j
+			if op == 'T' {
+				i++
+			}
+		}
+	}
+	return i, op == 'f' || op == 't', i != ed.pos
+}
+
+// firstNonBlank returns the index of the first non-<blank> character of
+// the line which begins at bol.
+func (ed *Editor) firstNonBlank(bol int) int {
+	i := bol
+	for i < len(ed.buf) && (ed.buf[i] == ' ' || ed.buf[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// nextWord returns the index of the beginning of the next word. If big
+// is true, a word consists of non-<blank> characters.
+func (ed *Editor) nextWord(i int, big bool) int {
+	if i < len(ed.buf) {
+		if c := wordClass(ed.buf[i], big); c != 0 {
+			for i < len(ed.buf) && wordClass(ed.buf[i], big) == c {
+				i++
+			}
+		}
+	}
+	for i < len(ed.buf) && isBlank(ed.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// prevWord returns the index of the beginning of the previous word. If
+// big is true, a word consists of non-<blank> characters.
+func (ed *Editor) prevWord(i int, big bool) int {
+	for i > 0 && isBlank(ed.buf[i-1]) {
+		i--
+	}
+	if i > 0 {
+		c := wordClass(ed.buf[i-1], big)
+		for i > 0 && wordClass(ed.buf[i-1], big) == c {
+			i--
+		}
+	}
+	return i
+}
+
+// endWord returns the index of the end of the next word. If big is true,
+// a word consists of non-<blank> characters.
+func (ed *Editor) endWord(i int, big bool) int {
+	i++
+	for i < len(ed.buf) && isBlank(ed.buf[i]) {
+		i++
+	}
+	if i >= len(ed.buf) {
+		return max(len(ed.buf)-1, 0)
+	}
+	c := wordClass(ed.buf[i], big)
+	for i+1 < len(ed.buf) && wordClass(ed.buf[i+1], big) == c {
+		i++
+	}
+	return i
+}
+
+// wordClass returns the class of r for the word motions.
+func wordClass(r rune, big bool) int {
+	switch {
+	case isBlank(r):
+		return 0
+	case big || isWordRune(r):
+		return 1
+	}
+	return 2
+}
@@ -2,7 +2,7 @@
 //
 // go.sh/interp :: arith.go
 //
-//   Copyright (c) 2021 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -12,6 +12,7 @@ package interp
 import __yyfmt__ "fmt"
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -159,9 +160,13 @@ func errLValue(op string) string {
 }
 
 func expand(yylex yyLexer, x expr) (int, bool) {
+	env := yylex.(*lexer).env
 	if x.s == "" {
 		return x.n, true
-	} else if v, set := yylex.(*lexer).env.Get(x.s); !set || v.Value == "" {
+	} else if v, set := env.Get(x.s); !set && env.Opts&NoUnset != 0 {
+		yylex.Error(fmt.Sprintf("%v: parameter is unset", x.s))
+		return 0, false
+	} else if !set || v.Value == "" {
 		return 0, true
 	} else if n, err := strconv.ParseInt(v.Value, 0, 0); err != nil {
 		yylex.Error(fmt.Sprintf("invalid number %q", v.Value))
@@ -171,6 +176,12 @@ func expand(yylex yyLexer, x expr) (int, bool) {
 	}
 }
 
+func assign(yylex yyLexer, name string, n int) {
+	if err := yylex.(*lexer).env.Set(name, strconv.Itoa(n)); err != nil {
+		yylex.Error(err.Error())
+	}
+}
+
 func calculate(yylex yyLexer, l expr, op string, r expr) (x expr, ok bool) {
 	if l, ok1 := expand(yylex, l); ok1 {
 		if r, ok2 := expand(yylex, r); ok2 {
@@ -229,8 +240,14 @@ func compare(yylex yyLexer, l expr, op string, r expr) (x expr) {
 }
 
 // Eval evaluates an arithmetic expression.
-func (env *ExecEnv) Eval(expr string) (n int, err error) {
-	l := newLexer(env, strings.NewReader(expr))
+func (env *ExecEnv) Eval(expr string) (int, error) {
+	return env.EvalContext(context.Background(), expr)
+}
+
+// EvalContext is like Eval, but stops the evaluation when the context is
+// done.
+func (env *ExecEnv) EvalContext(ctx context.Context, expr string) (n int, err error) {
+	l := newLexer(ctx, env, strings.NewReader(expr))
 	defer func() {
 		if e := recover(); e != nil {
 			l.Error(e.(error).Error())
@@ -715,7 +732,7 @@ yydefault:
 				yylex.Error(errLValue(yyDollar[2].op))
 			} else if n, ok := expand(yylex, yyDollar[1].expr); ok {
 				yyVAL.expr.n = n
-				yylex.(*lexer).env.Set(yyDollar[1].expr.s, strconv.Itoa(yyVAL.expr.n+1))
+				assign(yylex, yyDollar[1].expr.s, yyVAL.expr.n+1)
 			}
 		}
 	case 7:
@@ -726,7 +743,7 @@ yydefault:
 				yylex.Error(errLValue(yyDollar[2].op))
 			} else if n, ok := expand(yylex, yyDollar[1].expr); ok {
 				yyVAL.expr.n = n
-				yylex.(*lexer).env.Set(yyDollar[1].expr.s, strconv.Itoa(yyVAL.expr.n-1))
+				assign(yylex, yyDollar[1].expr.s, yyVAL.expr.n-1)
 			}
 		}
 	case 9:
@@ -737,7 +754,7 @@ yydefault:
 				yylex.Error(errLValue(yyDollar[1].op))
 			} else if n, ok := expand(yylex, yyDollar[2].expr); ok {
 				yyVAL.expr.n = n + 1
-				yylex.(*lexer).env.Set(yyDollar[2].expr.s, strconv.Itoa(yyVAL.expr.n))
+				assign(yylex, yyDollar[2].expr.s, yyVAL.expr.n)
 			}
 		}
 	case 10:
@@ -748,7 +765,7 @@ yydefault:
 				yylex.Error(errLValue(yyDollar[1].op))
 			} else if n, ok := expand(yylex, yyDollar[2].expr); ok {
 				yyVAL.expr.n = n - 1
-				yylex.(*lexer).env.Set(yyDollar[2].expr.s, strconv.Itoa(yyVAL.expr.n))
+				assign(yylex, yyDollar[2].expr.s, yyVAL.expr.n)
 			}
 		}
 	case 11:
@@ -900,7 +917,7 @@ yydefault:
 					yyVAL.expr, ok = calculate(yylex, yyDollar[1].expr, yyDollar[2].op[:len(yyDollar[2].op)-1], yyDollar[3].expr)
 				}
 				if ok {
-					yylex.(*lexer).env.Set(yyDollar[1].expr.s, strconv.Itoa(yyVAL.expr.n))
+					assign(yylex, yyDollar[1].expr.s, yyVAL.expr.n)
 				}
 			}
 		}
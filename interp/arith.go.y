@@ -2,7 +2,7 @@
 //
 // go.sh/interp :: arith.go
 //
-//   Copyright (c) 2021 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -10,6 +10,7 @@
 package interp
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -85,7 +86,7 @@ postfix_expr:
 				yylex.Error(errLValue($2))
 			} else if n, ok := expand(yylex, $1); ok {
 				$$.n = n
-				yylex.(*lexer).env.Set($1.s, strconv.Itoa($$.n + 1))
+				assign(yylex, $1.s, $$.n + 1)
 			}
 		}
 	|	postfix_expr DEC
@@ -95,7 +96,7 @@ postfix_expr:
 				yylex.Error(errLValue($2))
 			} else if n, ok := expand(yylex, $1); ok {
 				$$.n = n
-				yylex.(*lexer).env.Set($1.s, strconv.Itoa($$.n - 1))
+				assign(yylex, $1.s, $$.n - 1)
 			}
 		}
 
@@ -108,7 +109,7 @@ unary_expr:
 				yylex.Error(errLValue($1))
 			} else if n, ok := expand(yylex, $2); ok {
 				$$.n = n + 1
-				yylex.(*lexer).env.Set($2.s, strconv.Itoa($$.n))
+				assign(yylex, $2.s, $$.n)
 			}
 		}
 	|	DEC      unary_expr
@@ -118,7 +119,7 @@ unary_expr:
 				yylex.Error(errLValue($1))
 			} else if n, ok := expand(yylex, $2); ok {
 				$$.n = n - 1
-				yylex.(*lexer).env.Set($2.s, strconv.Itoa($$.n))
+				assign(yylex, $2.s, $$.n)
 			}
 		}
 	|	unary_op unary_expr
@@ -291,7 +292,7 @@ expr:
 					$$, ok = calculate(yylex, $1, $2[:len($2)-1], $3)
 				}
 				if ok {
-					yylex.(*lexer).env.Set($1.s, strconv.Itoa($$.n))
+					assign(yylex, $1.s, $$.n)
 				}
 			}
 		}
@@ -373,9 +374,13 @@ func errLValue(op string) string {
 }
 
 func expand(yylex yyLexer, x expr) (int, bool) {
+	env := yylex.(*lexer).env
 	if x.s == "" {
 		return x.n, true
-	} else if v, set := yylex.(*lexer).env.Get(x.s); !set || v.Value == "" {
+	} else if v, set := env.Get(x.s); !set && env.Opts&NoUnset != 0 {
+		yylex.Error(fmt.Sprintf("%v: parameter is unset", x.s))
+		return 0, false
+	} else if !set || v.Value == "" {
 		return 0, true
 	} else if n, err := strconv.ParseInt(v.Value, 0, 0); err != nil {
 		yylex.Error(fmt.Sprintf("invalid number %q", v.Value))
@@ -385,6 +390,12 @@ func expand(yylex yyLexer, x expr) (int, bool) {
 	}
 }
 
+func assign(yylex yyLexer, name string, n int) {
+	if err := yylex.(*lexer).env.Set(name, strconv.Itoa(n)); err != nil {
+		yylex.Error(err.Error())
+	}
+}
+
 func calculate(yylex yyLexer, l expr, op string, r expr) (x expr, ok bool) {
 	if l, ok1 := expand(yylex, l); ok1 {
 		if r, ok2 := expand(yylex, r); ok2 {
@@ -443,8 +454,14 @@ func compare(yylex yyLexer, l expr, op string, r expr) (x expr) {
 }
 
 // Eval evaluates an arithmetic expression.
-func (env *ExecEnv) Eval(expr string) (n int, err error) {
-	l := newLexer(env, strings.NewReader(expr))
+func (env *ExecEnv) Eval(expr string) (int, error) {
+	return env.EvalContext(context.Background(), expr)
+}
+
+// EvalContext is like Eval, but stops the evaluation when the context is
+// done.
+func (env *ExecEnv) EvalContext(ctx context.Context, expr string) (n int, err error) {
+	l := newLexer(ctx, env, strings.NewReader(expr))
 	defer func() {
 		if e := recover(); e != nil {
 			l.Error(e.(error).Error())
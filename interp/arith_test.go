@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: arith_test.go
 //
-//   Copyright (c) 2021-2024 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -9,6 +9,7 @@
 package interp_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/hattya/go.sh/interp"
@@ -333,6 +334,11 @@ var evalErrorTests = []struct {
 	{"1 >>  -1", "negative shift amount"},
 	{"N <<= -1", "negative shift amount"},
 	{"N >>= -1", "negative shift amount"},
+	// readonly
+	{"R = 1", "R: readonly variable"},
+	{"R += 1", "R: readonly variable"},
+	{"R++", "R: readonly variable"},
+	{"--R", "R: readonly variable"},
 }
 
 func TestEvalError(t *testing.T) {
@@ -342,6 +348,9 @@ func TestEvalError(t *testing.T) {
 	env.Set("N", "1")
 	env.Set("Z", "0z777")
 	env.Unset("_")
+	if _, err := run(env, "readonly R=0"); err != nil {
+		t.Fatal(err)
+	}
 	for _, tt := range evalErrorTests {
 		switch _, err := env.Eval(tt.expr); {
 		case err == nil:
@@ -351,3 +360,12 @@ func TestEvalError(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalContext(t *testing.T) {
+	env := interp.NewExecEnv(name)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := env.EvalContext(ctx, "1 + 1"); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,536 @@
+//
+// go.sh/interp :: builtin.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hattya/go.sh/parser"
+)
+
+// Builtin represents a built-in utility. It is called with the arguments
+// including the name of the utility, and returns the exit status.
+//
+// A built-in utility should write diagnostic messages to ExecEnv.Stderr
+// by itself. The returned error is treated as a fatal error of the shell.
+type Builtin func(ctx context.Context, env *ExecEnv, args []string) (int, error)
+
+var (
+	// spBuiltins is a table of the special built-in utilities.
+	spBuiltins map[string]Builtin
+	// builtins is a table of the regular built-in utilities.
+	builtins map[string]Builtin
+)
+
+// DefaultBuiltins returns a new table of the regular built-in utilities.
+func DefaultBuiltins() map[string]Builtin {
+	return maps.Clone(builtins)
+}
+
+func init() {
+	builtins = map[string]Builtin{
+		"[":       testBuiltin,
+		"alias":   aliasBuiltin,
+		"bg":      bgBuiltin,
+		"cd":      cdBuiltin,
+		"command": commandBuiltin,
+		"echo":    echoBuiltin,
+		"false":   falseBuiltin,
+		"fc":      fcBuiltin,
+		"fg":      fgBuiltin,
+		"getopts": getoptsBuiltin,
+		"jobs":    jobsBuiltin,
+		"kill":    killBuiltin,
+		"printf":  printfBuiltin,
+		"pwd":     pwdBuiltin,
+		"read":    readBuiltin,
+		"test":    testBuiltin,
+		"true":    trueBuiltin,
+		"type":    typeBuiltin,
+		"umask":   umaskBuiltin,
+		"unalias": unaliasBuiltin,
+		"wait":    waitBuiltin,
+	}
+	spBuiltins = map[string]Builtin{
+		"break":    breakBuiltin,
+		":":        colonBuiltin,
+		"continue": continueBuiltin,
+		".":        dotBuiltin,
+		"eval":     evalBuiltin,
+		"exec":     execBuiltin,
+		"exit":     exitBuiltin,
+		"export":   exportBuiltin,
+		"readonly": readonlyBuiltin,
+		"return":   returnBuiltin,
+		"set":      setBuiltin,
+		"shift":    shiftBuiltin,
+		"trap":     trapBuiltin,
+		"unset":    unsetBuiltin,
+	}
+}
+
+// breakBuiltin exits from the innermost n enclosing loops.
+func breakBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	return env.loopCtl(args, false)
+}
+
+// colonBuiltin does nothing.
+func colonBuiltin(context.Context, *ExecEnv, []string) (int, error) {
+	return 0, nil
+}
+
+// continueBuiltin continues the next iteration of the n-th enclosing
+// loop.
+func continueBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	return env.loopCtl(args, true)
+}
+
+func (env *ExecEnv) loopCtl(args []string, cont bool) (int, error) {
+	n := 1
+	switch len(args) {
+	case 1:
+	case 2:
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return 1, fmt.Errorf("%v: %v: invalid number", args[0], args[1])
+		}
+	default:
+		return 1, fmt.Errorf("%v: too many arguments", args[0])
+	}
+	if env.loop == 0 {
+		return 0, nil
+	}
+	return 0, &loopCtl{
+		n:    min(n, env.loop),
+		cont: cont,
+	}
+}
+
+// dotBuiltin executes commands from the file in the current environment.
+func dotBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) < 2 {
+		return 2, fmt.Errorf("%v: file argument required", args[0])
+	}
+	v, _ := env.Get("PATH")
+	path, err := env.search(v.Value, args[1], env.findFile)
+	if err != nil {
+		return 1, fmt.Errorf("%v: %v: %w", args[0], args[1], err)
+	}
+	b, err := env.readFile(path)
+	if err != nil {
+		var perr *os.PathError
+		if errors.As(err, &perr) {
+			err = perr.Err
+		}
+		return 1, fmt.Errorf("%v: %v: %w", args[0], args[1], err)
+	}
+	if env.MaxCallDepth > 0 && env.depth >= env.MaxCallDepth {
+		return 1, fmt.Errorf("%v: %w (%v)", args[0], ErrCallDepth, env.MaxCallDepth)
+	}
+	env.depth++
+	defer func() { env.depth-- }()
+
+	status, err := env.source(ctx, path, strings.NewReader(string(b)))
+	var rc *returnCtl
+	if errors.As(err, &rc) {
+		return rc.status, nil
+	}
+	return status, err
+}
+
+// findFile reports whether the file named by the path is a regular file.
+func (env *ExecEnv) findFile(path string) (string, error) {
+	switch fi, err := env.stat(path); {
+	case err != nil:
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", os.ErrPermission
+	case fi.IsDir():
+		return "", os.ErrPermission
+	}
+	return path, nil
+}
+
+// evalBuiltin constructs a command by concatenating arguments, and
+// executes it in the current environment.
+func evalBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	return env.source(ctx, env.Args[0], strings.NewReader(strings.Join(args[1:], " ")))
+}
+
+// source reads commands from r, and executes them in the current
+// environment.
+func (env *ExecEnv) source(ctx context.Context, name string, r *strings.Reader) (status int, err error) {
+	pr, _ := parser.NewReader(r)
+	for r.Len() > 0 {
+		cmds, _, err := parser.ParseCommands(env, name, pr)
+		if err != nil {
+			return 2, err
+		}
+		if status, err = env.runList(ctx, cmds); err != nil {
+			return status, err
+		}
+	}
+	return
+}
+
+// execBuiltin executes the utility in place of the shell. The I/O
+// redirections of exec without arguments are performed by runSimpleCmd.
+func execBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) == 1 {
+		return 0, nil
+	}
+	status, err := env.exec(ctx, args[1:], nil)
+	if err != nil {
+		return status, err
+	}
+	// the shell was replaced by the utility
+	delete(env.traps, "EXIT")
+	return status, &ExitError{Status: status}
+}
+
+// exitBuiltin exits the shell with the exit status n. If n is omitted,
+// the exit status is that of the last command executed.
+func exitBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	status := env.status
+	switch len(args) {
+	case 1:
+	case 2:
+		n, err := strconv.ParseUint(args[1], 10, 0)
+		if err != nil {
+			return 2, fmt.Errorf("%v: %v: numeric argument required", args[0], args[1])
+		}
+		status = int(n & 0xff)
+	default:
+		return 2, fmt.Errorf("%v: too many arguments", args[0])
+	}
+	return status, &ExitError{Status: status}
+}
+
+// exportBuiltin sets the export attribute for variables.
+func exportBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	return env.setAttr(args, true, false)
+}
+
+// readonlyBuiltin sets the readonly attribute for variables.
+func readonlyBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	return env.setAttr(args, false, true)
+}
+
+func (env *ExecEnv) setAttr(args []string, export, readonly bool) (int, error) {
+	if len(args) == 1 || (len(args) == 2 && args[1] == "-p") {
+		for _, v := range env.sortedVars() {
+			if (export && v.Export) || (readonly && v.ReadOnly) {
+				if v.unset {
+					fmt.Fprintf(env.Stdout, "%v %v\n", args[0], v.Name)
+				} else {
+					fmt.Fprintf(env.Stdout, "%v %v=%v\n", args[0], v.Name, quote(v.Value))
+				}
+			}
+		}
+		return 0, nil
+	}
+
+	cmd := args[0]
+	args = args[1:]
+	if args[0] == "--" {
+		args = args[1:]
+	}
+	for _, s := range args {
+		var value *string
+		name := s
+		if i := strings.IndexByte(s, '='); i != -1 {
+			name = s[:i]
+			value = new(string)
+			*value = s[i+1:]
+		}
+		if !env.isName(name) {
+			return 1, fmt.Errorf("%v: %v: invalid name", cmd, name)
+		}
+		if err := env.attr(name, value, export, readonly); err != nil {
+			return 1, fmt.Errorf("%v: %w", cmd, err)
+		}
+	}
+	return 0, nil
+}
+
+// returnBuiltin returns from a function or a dot script with the exit
+// status n. If n is omitted, the exit status is that of the last command
+// executed.
+func returnBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	status := env.status
+	switch len(args) {
+	case 1:
+	case 2:
+		n, err := strconv.ParseUint(args[1], 10, 0)
+		if err != nil {
+			return 2, fmt.Errorf("%v: %v: numeric argument required", args[0], args[1])
+		}
+		status = int(n & 0xff)
+	default:
+		return 2, fmt.Errorf("%v: too many arguments", args[0])
+	}
+	if env.depth == 0 {
+		return 1, fmt.Errorf("%v: can only return from a function or a dot script", args[0])
+	}
+	return status, &returnCtl{status: status}
+}
+
+// setBuiltin sets or unsets options and positional parameters.
+func setBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) == 1 {
+		for _, v := range env.sortedVars() {
+			if !v.unset {
+				fmt.Fprintf(env.Stdout, "%v=%v\n", v.Name, quote(v.Value))
+			}
+		}
+		return 0, nil
+	}
+
+	opts := env.Opts
+	set := false
+	i := 1
+Options:
+	for ; i < len(args); i++ {
+		s := args[i]
+		switch {
+		case s == "--" || s == "-":
+			i++
+			set = s == "--"
+			break Options
+		case len(s) < 2 || (s[0] != '-' && s[0] != '+'):
+			break Options
+		}
+		for _, r := range s[1:] {
+			var o Option
+			if r == 'o' {
+				if i+1 == len(args) {
+					env.printOpts(s[0] == '+')
+					continue
+				}
+				i++
+				j := slices.Index(optionNames[:], args[i])
+				if j == -1 {
+					return 2, fmt.Errorf("%v: %v: invalid option name", args[0], args[i])
+				}
+				o = 1 << j
+			} else {
+				j := strings.IndexRune(optionString, r)
+				if j == -1 || r == ' ' {
+					return 2, fmt.Errorf("%v: %c%c: invalid option", args[0], s[0], r)
+				}
+				o = 1 << j
+			}
+			if s[0] == '-' {
+				opts |= o
+			} else {
+				opts &^= o
+			}
+		}
+	}
+	env.Opts = opts
+	env.setNotify()
+	if set || i < len(args) {
+		env.Args = append([]string{env.Args[0]}, args[i:]...)
+	}
+	return 0, nil
+}
+
+// printOpts prints the current option settings. If cmd is true, they are
+// printed in a format that is suitable for reinput to the shell.
+func (env *ExecEnv) printOpts(cmd bool) {
+	for i, name := range optionNames {
+		on := env.Opts&(1<<i) != 0
+		switch {
+		case !cmd && on:
+			fmt.Fprintf(env.Stdout, "%-15v on\n", name)
+		case !cmd:
+			fmt.Fprintf(env.Stdout, "%-15v off\n", name)
+		case on:
+			fmt.Fprintf(env.Stdout, "set -o %v\n", name)
+		default:
+			fmt.Fprintf(env.Stdout, "set +o %v\n", name)
+		}
+	}
+}
+
+// shiftBuiltin shifts the positional parameters.
+func shiftBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	n := 1
+	switch len(args) {
+	case 1:
+	case 2:
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return 1, fmt.Errorf("%v: %v: invalid number", args[0], args[1])
+		}
+	default:
+		return 1, fmt.Errorf("%v: too many arguments", args[0])
+	}
+	if n > len(env.Args)-1 {
+		return 1, fmt.Errorf("%v: %v: shift count out of range", args[0], n)
+	}
+	env.Args = append([]string{env.Args[0]}, env.Args[1+n:]...)
+	return 0, nil
+}
+
+// trapBuiltin sets or prints the actions for conditions.
+func trapBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	args = args[1:]
+	print := false
+	switch {
+	case len(args) == 0:
+		print = true
+	case args[0] == "-p":
+		args = args[1:]
+		print = true
+	case args[0] == "--":
+		args = args[1:]
+	}
+	if print {
+		// the traps of the parent are printed in a subshell until they
+		// are modified
+		traps := env.traps
+		if env.ptraps != nil {
+			traps = env.ptraps
+		}
+		if len(args) == 0 {
+			for _, k := range slices.Sorted(maps.Keys(traps)) {
+				fmt.Fprintf(env.Stdout, "trap -- %v %v\n", quote(traps[k]), k)
+			}
+			return 0, nil
+		}
+		for _, s := range args {
+			k, ok := trapCond(s)
+			if !ok {
+				return 1, fmt.Errorf("trap: %v: invalid condition", s)
+			}
+			if action, ok := traps[k]; ok {
+				fmt.Fprintf(env.Stdout, "trap -- %v %v\n", quote(action), k)
+			} else {
+				fmt.Fprintf(env.Stdout, "trap -- - %v\n", k)
+			}
+		}
+		return 0, nil
+	}
+
+	var action string
+	reset := false
+	if _, err := strconv.ParseUint(args[0], 10, 0); err == nil {
+		reset = true
+	} else {
+		action = args[0]
+		reset = action == "-"
+		args = args[1:]
+	}
+	for _, s := range args {
+		k, ok := trapCond(s)
+		if !ok {
+			return 1, fmt.Errorf("trap: %v: invalid condition", s)
+		}
+		env.setTrap(k, action, reset)
+	}
+	return 0, nil
+}
+
+// trapCond returns the canonical name of the condition.
+func trapCond(s string) (string, bool) {
+	switch s {
+	case "0":
+		return "EXIT", true
+	case "1":
+		return "HUP", true
+	case "2":
+		return "INT", true
+	case "3":
+		return "QUIT", true
+	case "6":
+		return "ABRT", true
+	case "9":
+		return "KILL", true
+	case "14":
+		return "ALRM", true
+	case "15":
+		return "TERM", true
+	}
+	s = strings.TrimPrefix(s, "SIG")
+	switch s {
+	case "EXIT", "ERR",
+		"ABRT", "ALRM", "BUS", "CHLD", "CONT", "FPE", "HUP", "ILL", "INT", "KILL", "PIPE", "PROF", "QUIT", "SEGV",
+		"STOP", "SYS", "TERM", "TRAP", "TSTP", "TTIN", "TTOU", "URG", "USR1", "USR2", "VTALRM", "WINCH", "XCPU", "XFSZ":
+		return s, true
+	}
+	return "", false
+}
+
+// unsetBuiltin unsets variables or functions.
+func unsetBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	fn := false
+	args = args[1:]
+	for len(args) > 0 {
+		switch args[0] {
+		case "-f":
+			fn = true
+		case "-v":
+			fn = false
+		case "--":
+			args = args[1:]
+			fallthrough
+		default:
+			goto Unset
+		}
+		args = args[1:]
+	}
+Unset:
+	for _, name := range args {
+		switch {
+		case fn:
+			env.funcs.delete(name)
+		case !env.isName(name):
+			return 1, fmt.Errorf("unset: %v: invalid name", name)
+		default:
+			if err := env.unset(name); err != nil {
+				return 1, fmt.Errorf("unset: %w", err)
+			}
+		}
+	}
+	return 0, nil
+}
+
+// sortedVars returns the variables, including the ones which only have
+// attributes, sorted by their names.
+func (env *ExecEnv) sortedVars() []Var {
+	vars := slices.Collect(maps.Values(env.vars.m))
+	slices.SortFunc(vars, func(a, b Var) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return vars
+}
+
+// quote quotes s for reinput to the shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExitError represents the exit of the shell by the exit or exec special
+// built-in utilities.
+type ExitError struct {
+	Status int
+}
+
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Status)
+}
@@ -0,0 +1,306 @@
+//
+// go.sh/interp :: builtin_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var spBuiltinTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	// :
+	{":", "", 0},
+	{": foo bar", "", 0},
+	{"helper exit 3; :", "", 0},
+	// .
+	{". $D/f", "foo\n", 0},
+	{"PATH=$D; . f", "foo\n", 0},
+	{"BAR=bar; . $D/f; helper echo $FOO $BAR", "foo\nfoo bar\n", 0},
+	{"helper echo 'return 3; helper echo foo' >$D/f; . $D/f", "", 3},
+	{"helper echo >$D/f; . $D/f", "", 0},
+	// eval
+	{"eval helper echo foo", "foo\n", 0},
+	{"eval 'helper echo foo;' helper echo bar", "foo\nbar\n", 0},
+	{"eval 'FOO=foo'; helper echo $FOO", "foo\n", 0},
+	{"FOO='helper echo foo'; eval $FOO", "foo\n", 0},
+	{"eval 'helper exit 3'", "", 3},
+	{"eval", "", 0},
+	{"helper exit 3; eval ''", "", 0},
+	{"eval 'for i in 1 2; do helper echo $i; done'", "1\n2\n", 0},
+	{"eval 'helper echo foo\nhelper echo bar'", "foo\nbar\n", 0},
+	{"eval 'f() {\nhelper echo foo\n}\nf'", "foo\n", 0},
+	// exec
+	{"export GO_SH_HELPER=1 GORACE=atexit_sleep_ms=0; exec \"$EXE\" echo foo; helper echo bar", "foo\n", 0},
+	{"export GO_SH_HELPER=1 GORACE=atexit_sleep_ms=0; exec \"$EXE\" exit 3; helper echo bar", "", 3},
+	{"exec >$D/f; helper echo foo", "", 0},
+	{"exec 3>$D/f; helper echo foo >&3; helper echo bar >&3; helper cat <$D/f >&2", "", 0},
+	{"exec 3>&1; (exec 3>&-); helper echo foo >&3", "foo\n", 0},
+	{"exec 3>&1; { exec 3>&-; }; helper echo foo >&3", "", 1},
+	{"(exec >$D/f); helper echo foo", "foo\n", 0},
+	{"FOO=foo exec; helper echo $FOO", "foo\n", 0},
+	{"GO_SH_HELPER=1 GORACE=atexit_sleep_ms=0 FOO=foo exec \"$EXE\" getenv GO_SH_HELPER FOO", "GO_SH_HELPER=1\nFOO=foo\n", 0},
+	// exit
+	{"exit; helper echo foo", "", 0},
+	{"exit 3; helper echo foo", "", 3},
+	{"helper exit 3; exit", "", 3},
+	{"exit 256", "", 0},
+	{"(exit 3); helper echo $?", "3\n", 0},
+	{"f() { exit 3; }; f; helper echo foo", "", 3},
+	{"for i in 1 2; do exit $i; done", "", 1},
+	{"X=$(helper echo foo; exit 3; helper echo bar); helper echo $X $?", "foo 3\n", 0},
+	// export
+	{"export FOO=foo; helper getenv FOO", "FOO=foo\n", 0},
+	{"FOO=foo; export FOO; helper getenv FOO", "FOO=foo\n", 0},
+	{"export FOO; FOO=foo; helper getenv FOO", "FOO=foo\n", 0},
+	{"export FOO; helper getenv FOO; helper echo ${FOO-unset}", "FOO is unset\nunset\n", 0},
+	{"export FOO=foo BAR=bar; helper getenv FOO BAR", "FOO=foo\nBAR=bar\n", 0},
+	{"export -- FOO=foo; helper getenv FOO", "FOO=foo\n", 0},
+	{"FOO=foo export FOO; helper getenv FOO", "FOO=foo\n", 0},
+	{"(export FOO=foo); helper getenv FOO", "FOO is unset\n", 0},
+	// readonly
+	{"readonly FOO=foo; helper echo $FOO", "foo\n", 0},
+	{"FOO=foo; readonly FOO; helper echo $FOO", "foo\n", 0},
+	{"readonly FOO=foo; helper getenv FOO", "FOO is unset\n", 0},
+	{"export FOO=foo; readonly FOO; helper getenv FOO", "FOO=foo\n", 0},
+	{"export FOO=foo; FOO=bar; helper getenv FOO", "FOO=bar\n", 0},
+	{"set -a; readonly FOO=foo; helper getenv FOO", "FOO=foo\n", 0},
+	// return
+	{"f() { return; }; f", "", 0},
+	// set
+	{"set -- foo bar; helper echo $# $1 $2", "2 foo bar\n", 0},
+	{"set foo bar; helper echo $# $1 $2", "2 foo bar\n", 0},
+	{"set foo; set --; helper echo $#", "0\n", 0},
+	{"set foo; set -C; helper echo $- $1", "C foo\n", 0},
+	{"set foo; set - bar; helper echo $1", "bar\n", 0},
+	{"set -- -x; helper echo $1", "-x\n", 0},
+	{"set -aCu; helper echo $-", "aCu\n", 0},
+	{"set -aCu; set +C; helper echo $-", "au\n", 0},
+	{"set -o noclobber -o nounset; helper echo $-", "Cu\n", 0},
+	{"set -C; set +o noclobber; helper echo $-", "\n", 0},
+	{"set -a; FOO=foo; helper getenv FOO", "FOO=foo\n", 0},
+	{"set -o | helper wc", "13\n", 0},
+	{"set -- 1 2; for i do helper echo $i; done", "1\n2\n", 0},
+	{"f() { set -- foo; helper echo $1; }; set -- bar; f; helper echo $1", "foo\nbar\n", 0},
+	// shift
+	{"set -- 1 2 3; shift; helper echo $# $@", "2 2 3\n", 0},
+	{"set -- 1 2 3; shift 2; helper echo $# $@", "1 3\n", 0},
+	{"set -- 1 2 3; shift 3; helper echo $#", "0\n", 0},
+	{"set -- 1 2 3; shift 0; helper echo $#", "3\n", 0},
+	{"f() { shift; helper echo $@; }; f 1 2 3", "2 3\n", 0},
+	// trap
+	{"trap 'helper echo foo' EXIT; trap", "trap -- 'helper echo foo' EXIT\n", 0},
+	{"trap 'helper echo foo' 0 INT; trap", "trap -- 'helper echo foo' EXIT\ntrap -- 'helper echo foo' INT\n", 0},
+	{"trap '' SIGTERM; trap", "trap -- '' TERM\n", 0},
+	{"trap -- \"echo 'foo'\" TERM; trap", "trap -- 'echo '\\''foo'\\''' TERM\n", 0},
+	{"trap : INT TERM; trap - INT; trap", "trap -- ':' TERM\n", 0},
+	{"trap : INT TERM; trap 2 INT; trap", "trap -- ':' TERM\n", 0},
+	{"trap : INT; trap -p INT TERM", "trap -- ':' INT\ntrap -- - TERM\n", 0},
+	{"trap : INT; (trap - INT); trap", "trap -- ':' INT\n", 0},
+	{"trap 'helper echo $?' EXIT; exit 3; helper echo foo", "3\n", 3},
+	{"trap 'helper echo foo' EXIT; trap 'helper echo bar' EXIT; exit", "bar\n", 0},
+	{"trap 'exit 5' EXIT; exit 3", "", 5},
+	{"trap 'helper echo foo' EXIT; trap - EXIT; exit", "", 0},
+	{"trap 'helper echo foo' EXIT; (helper echo bar)", "bar\n", 0},
+	{"(trap 'helper echo foo' EXIT; helper echo bar); helper echo baz", "bar\nfoo\nbaz\n", 0},
+	{"(trap 'helper echo foo' EXIT; exit 3); helper echo $?", "foo\n3\n", 0},
+	{"X=$(trap 'helper echo foo' EXIT; helper echo bar); helper echo $X", "bar foo\n", 0},
+	{"trap : INT; X=$(trap); helper echo \"$X\"", "trap -- ':' INT\n", 0},
+	{"trap : INT; (trap 'helper echo foo' TERM; trap)", "trap -- 'helper echo foo' TERM\n", 0},
+	{"trap '' INT; trap : TERM; (trap - TERM; trap)", "trap -- '' INT\n", 0},
+	{"trap 'helper echo ERR $?' ERR; helper exit 3; helper echo $?", "ERR 3\n3\n", 0},
+	{"trap 'helper echo ERR' ERR; helper exit 1 || helper exit 2", "ERR\n", 2},
+	{"trap 'helper echo ERR' ERR; helper exit 1 && :", "", 1},
+	{"trap 'helper echo ERR' ERR; ! helper exit 0", "", 1},
+	{"trap 'helper echo ERR' ERR; if helper exit 1; then :; fi", "", 0},
+	{"trap 'helper echo ERR' ERR; while helper exit 1; do :; done", "", 0},
+	{"trap 'helper echo ERR' ERR; { helper exit 1; }", "ERR\n", 1},
+	{"trap 'helper echo ERR' ERR; f() { helper exit 1; }; f", "ERR\n", 1},
+	{"trap 'helper echo ERR' ERR; (helper exit 1)", "ERR\n", 1},
+	{"trap 'helper echo ERR; false' ERR; false", "ERR\n", 1},
+	// unset
+	{"FOO=foo; unset FOO; helper echo ${FOO-unset}", "unset\n", 0},
+	{"FOO=foo; unset -v FOO; helper echo ${FOO-unset}", "unset\n", 0},
+	{"FOO=foo; BAR=bar; unset FOO BAR; helper echo ${FOO-unset} ${BAR-unset}", "unset unset\n", 0},
+	{"unset FOO", "", 0},
+	{"export FOO=foo; unset FOO; FOO=bar; helper getenv FOO", "FOO is unset\n", 0},
+	{"f() { helper echo foo; }; unset -f f; f", "", 127},
+	{"f() { helper echo foo; }; unset f; f", "foo\n", 0},
+	// variable assignments
+	{"FOO=foo :; helper echo $FOO", "foo\n", 0},
+	{"FOO=foo eval 'helper echo $FOO'", "foo\n", 0},
+}
+
+func TestSpBuiltin(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range spBuiltinTests {
+		env, stdout, _ := newTestEnv(t)
+		dir := t.TempDir()
+		env.Set("D", dir)
+		env.Set("EXE", exe)
+		if err := os.WriteFile(filepath.Join(dir, "f"), []byte("FOO=foo; helper echo $FOO\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		status, err := run(env, tt.src)
+		var eerr *interp.ExitError
+		if errors.As(err, &eerr) {
+			status = eerr.Status
+			err = nil
+		}
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+var spBuiltinErrorTests = []struct {
+	src string
+	err string
+}{
+	{"for i in 1; do break 0; done", "break: 0: invalid number"},
+	{"for i in 1; do break x; done", "break: x: invalid number"},
+	{"for i in 1; do break 1 2; done", "break: too many arguments"},
+	{"for i in 1; do continue 0; done", "continue: 0: invalid number"},
+	{".", ".: file argument required"},
+	{". $D/go.sh-not-found", ".: $D/go.sh-not-found: not found"},
+	{"eval 'if'", name + ":1:1: syntax error: unexpected EOF"},
+	{"exit x", "exit: x: numeric argument required"},
+	{"exit 1 2", "exit: too many arguments"},
+	{"export -x", "export: -x: invalid name"},
+	{"readonly FOO=foo; export FOO=bar", "export: FOO: readonly variable"},
+	{"readonly FOO=foo; readonly FOO=bar", "readonly: FOO: readonly variable"},
+	{"readonly FOO=foo; FOO=bar", "FOO: readonly variable"},
+	{"readonly FOO=foo; FOO=bar helper exit 0", "FOO: readonly variable"},
+	{"readonly FOO=foo; FOO=bar :", "FOO: readonly variable"},
+	{"readonly FOO=foo; for FOO in bar; do :; done", "FOO: readonly variable"},
+	{"readonly FOO=foo; unset FOO", "unset: FOO: readonly variable"},
+	{"readonly FOO; FOO=bar", "FOO: readonly variable"},
+	{"readonly FOO; : ${FOO=bar}", "FOO: readonly variable"},
+	{"return", "return: can only return from a function or a dot script"},
+	{"f() { return x; }; f", "return: x: numeric argument required"},
+	{"set -o go.sh", "set: go.sh: invalid option name"},
+	{"set -z", "set: -z: invalid option"},
+	{"set +z", "set: +z: invalid option"},
+	{"set -- 1; shift 2", "shift: 2: shift count out of range"},
+	{"shift x", "shift: x: invalid number"},
+	{"trap : go.sh", "trap: go.sh: invalid condition"},
+	{"unset 1", "unset: 1: invalid name"},
+	{": >$D", ""},
+}
+
+func TestSpBuiltinError(t *testing.T) {
+	for _, tt := range spBuiltinErrorTests {
+		env, _, _ := newTestEnv(t)
+		env.Set("D", t.TempDir())
+		src := tt.src
+		if v, _ := env.Get("D"); tt.err != "" {
+			tt.err = os.Expand(tt.err, func(string) string { return v.Value })
+		}
+		switch _, err := run(env, src); {
+		case err == nil:
+			t.Errorf("%q: expected error", src)
+		case tt.err != "" && err.Error() != tt.err:
+			t.Errorf("%q: expected %q, got %q", src, tt.err, err)
+		}
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	for i := range 13 {
+		o := interp.Option(1 << i)
+		env.Opts = o
+		stdout.Reset()
+		if _, err := run(env, "set +o"); err != nil {
+			t.Fatal(err)
+		}
+		save := stdout.String()
+		env.Opts = (1<<13 - 1) &^ o
+		if _, err := run(env, save); err != nil {
+			t.Fatal(err)
+		}
+		if g, e := env.Opts, o; g != e {
+			t.Errorf("expected %v, got %v", e, g)
+		}
+	}
+}
+
+func TestTrapOutput(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	if _, err := run(env, "trap \"echo 'foo'\" EXIT; trap '' INT; trap 'X=1; Y=2' ERR TERM"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(env, "trap"); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	sub, stdout, _ := newTestEnv(t)
+	for _, src := range []string{out, "trap"} {
+		if _, err := run(sub, src); err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+	}
+	if g, e := stdout.String(), out; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+func TestSetVars(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	if _, err := run(env, "FOO=\"'foo'\"; BAR='bar baz'; export BAR; readonly BAZ=\"\"; export QUX"); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{"set", "export -p", "readonly -p"} {
+		stdout.Reset()
+		if _, err := run(env, src); err != nil {
+			t.Fatal(err)
+		}
+		out := stdout.String()
+		sub, _, _ := newTestEnv(t)
+		for _, k := range []string{"FOO", "BAR", "BAZ", "QUX"} {
+			sub.Unset(k)
+		}
+		if _, err := run(sub, out); err != nil {
+			t.Fatalf("%v: %v", src, err)
+		}
+		for _, k := range []string{"FOO", "BAR", "BAZ", "QUX"} {
+			g, gset := sub.Get(k)
+			e, eset := env.Get(k)
+			switch src {
+			case "set":
+				if gset != eset || g.Value != e.Value {
+					t.Errorf("%v: %v: expected %#v, got %#v", src, k, e, g)
+				}
+			case "export -p":
+				if e.Export && (gset != eset || g.Value != e.Value || !g.Export) {
+					t.Errorf("%v: %v: expected %#v, got %#v", src, k, e, g)
+				}
+			case "readonly -p":
+				if e.ReadOnly && (gset != eset || g.Value != e.Value || !g.ReadOnly) {
+					t.Errorf("%v: %v: expected %#v, got %#v", src, k, e, g)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,562 @@
+//
+// go.sh/interp :: complete.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CompletionKind represents the syntactic context of the word at the
+// cursor.
+type CompletionKind int
+
+const (
+	// CompleteNone indicates that the word cannot be completed, such as
+	// in a comment or an arithmetic expansion.
+	CompleteNone CompletionKind = iota
+	// CompleteCommand indicates the command name of a simple command.
+	CompleteCommand
+	// CompleteArgument indicates an argument of a simple command, or the
+	// value of a variable assignment.
+	CompleteArgument
+	// CompleteVariable indicates a variable name after "$" or "${".
+	CompleteVariable
+	// CompleteRedirection indicates the target of a redirection.
+	CompleteRedirection
+	// CompleteTilde indicates a login name of a tilde-prefix.
+	CompleteTilde
+)
+
+var completionKinds = [...]string{
+	CompleteNone:        "none",
+	CompleteCommand:     "command",
+	CompleteArgument:    "argument",
+	CompleteVariable:    "variable",
+	CompleteRedirection: "redirection",
+	CompleteTilde:       "tilde",
+}
+
+func (k CompletionKind) String() string {
+	if 0 <= k && int(k) < len(completionKinds) {
+		return completionKinds[k]
+	}
+	return "unknown"
+}
+
+// Completion represents the candidates for the word at the cursor.
+type Completion struct {
+	Kind CompletionKind
+
+	// Start and End are the byte offsets of the text which is replaced
+	// by a candidate. End is the cursor.
+	Start, End int
+
+	// Word is the text being completed with the quotes removed.
+	Word string
+
+	// Candidates holds the sorted texts which replace the range
+	// [Start, End) of the line. They are quoted in the same manner as
+	// the word, and a pathname of a directory ends with "/".
+	Candidates []string
+}
+
+// Complete returns the candidates for the word at the cursor, which is
+// the byte offset pos of the line. The line may be incomplete, and only
+// the text before the cursor is examined as the lexer does.
+//
+// The candidates for a command name are the functions, built-in
+// utilities, aliases, and executables in the PATH variable. The
+// candidates for an argument and the target of a redirection are the
+// pathnames, and the ones for a variable name are the variables which
+// are set.
+func (env *ExecEnv) Complete(ctx context.Context, line string, pos int) *Completion {
+	pos = min(max(pos, 0), len(line))
+	_, wc := scanList(line[:pos], 0, 0)
+	c := &Completion{
+		Kind:  wc.kind,
+		Start: wc.start,
+		End:   pos,
+	}
+	word, ok := unquoteWord(line[wc.start:pos])
+	if !ok {
+		return c
+	}
+	c.Word = word
+
+	switch c.Kind {
+	case CompleteCommand:
+		if strings.Contains(word, "/") {
+			c.Candidates = env.completePath(ctx, word, wc.quote, true)
+		} else {
+			c.Candidates = env.completeCmdName(ctx, word, wc.quote)
+		}
+	case CompleteArgument, CompleteRedirection:
+		c.Candidates = env.completePath(ctx, word, wc.quote, false)
+	case CompleteVariable:
+		for _, v := range env.vars.m {
+			if !v.unset && strings.HasPrefix(v.Name, word) {
+				if wc.brace {
+					c.Candidates = append(c.Candidates, v.Name+"}")
+				} else {
+					c.Candidates = append(c.Candidates, v.Name)
+				}
+			}
+		}
+		slices.Sort(c.Candidates)
+	case CompleteTilde:
+		for _, name := range users() {
+			if strings.HasPrefix(name, word[1:]) {
+				c.Candidates = append(c.Candidates, "~"+name+"/")
+			}
+		}
+		slices.Sort(c.Candidates)
+		c.Candidates = slices.Compact(c.Candidates)
+	}
+	return c
+}
+
+// completeCmdName returns the command names which begin with the prefix.
+func (env *ExecEnv) completeCmdName(ctx context.Context, prefix string, quote byte) []string {
+	var names []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	for name := range env.funcs.m {
+		add(name)
+	}
+	for name := range spBuiltins {
+		add(name)
+	}
+	for name := range env.Builtins {
+		add(name)
+	}
+	for name := range env.Aliases {
+		add(name)
+	}
+	v, _ := env.Get("PATH")
+	for _, dir := range filepath.SplitList(v.Value) {
+		if dir == "" {
+			dir = "."
+		}
+		paths, _ := env.glob(ctx, escapePattern(filepath.ToSlash(dir))+"/"+escapePattern(prefix)+"*")
+		for _, p := range paths {
+			if _, err := env.findExecutable(p); err == nil {
+				add(p[strings.LastIndexByte(p, '/')+1:])
+			}
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+	for i, name := range names {
+		names[i] = complQuote(name, quote, true)
+	}
+	return names
+}
+
+// completePath returns the pathnames which begin with the prefix in the
+// order of pathname expansion. If exec is true, only the directories and
+// executables are returned.
+func (env *ExecEnv) completePath(ctx context.Context, prefix string, quote byte, exec bool) []string {
+	// tilde-prefix
+	var tilde, home string
+	if i := strings.IndexByte(prefix, '/'); i > 0 && prefix[0] == '~' && quote == 0 {
+		if home = env.homeDir(prefix[1:i]); home == "" {
+			return nil
+		}
+		tilde = prefix[:i]
+		prefix = home + prefix[i:]
+	}
+
+	paths, _ := env.glob(ctx, escapePattern(prefix)+"*")
+	var names []string
+	for _, p := range paths {
+		fi, err := env.stat(p)
+		switch {
+		case err != nil:
+			continue
+		case fi.IsDir():
+			p += "/"
+		case exec:
+			if _, err := env.findExecutable(p); err != nil {
+				continue
+			}
+		}
+		s := p
+		if tilde != "" {
+			s = strings.TrimPrefix(s, home)
+		}
+		s = complQuote(s, quote, !strings.HasSuffix(s, "/"))
+		names = append(names, tilde+s)
+	}
+	return names
+}
+
+// escapePattern escapes the special characters of pattern matching
+// notation in s.
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '*', '?', '[':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// complQuote quotes s by the quote if it is "'" or "\"", and closes it if
+// closed is true. Otherwise, the special characters are escaped by
+// <backslash>es.
+func complQuote(s string, quote byte, closed bool) string {
+	var b strings.Builder
+	switch quote {
+	case '\'':
+		b.WriteByte('\'')
+		b.WriteString(strings.ReplaceAll(s, "'", `'\''`))
+	case '"':
+		b.WriteByte('"')
+		for _, r := range s {
+			switch r {
+			case '$', '`', '"', '\\':
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+	default:
+		for i, r := range s {
+			switch r {
+			case '|', '&', ';', '<', '>', '(', ')', '$', '`', '\\', '"', '\'', ' ', '\t', '\n', '*', '?', '[':
+				b.WriteByte('\\')
+			case '#', '~':
+				if i == 0 {
+					b.WriteByte('\\')
+				}
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	if closed {
+		b.WriteByte(quote)
+	}
+	return b.String()
+}
+
+// unquoteWord performs quote removal on the word. It reports false if
+// the word contains expansions.
+func unquoteWord(w string) (string, bool) {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(w); i++ {
+		switch c := w[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				b.WriteByte(c)
+			}
+		case c == '\\':
+			if i++; i < len(w) {
+				switch {
+				case w[i] == '\n':
+				case quote == '"' && !strings.ContainsRune("$`\"\\", rune(w[i])):
+					b.WriteByte('\\')
+					fallthrough
+				default:
+					b.WriteByte(w[i])
+				}
+			}
+		case c == '\'' && quote == 0:
+			quote = '\''
+		case c == '"':
+			if quote == '"' {
+				quote = 0
+			} else {
+				quote = '"'
+			}
+		case c == '$' || c == '`':
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// wordContext represents the context of the word at the cursor.
+type wordContext struct {
+	kind  CompletionKind
+	start int
+	quote byte // quote of the word
+	brace bool // variable name in braces
+}
+
+// keepCmdPos is the set of the reserved words which are followed by a
+// command.
+var keepCmdPos = map[string]bool{
+	"!":     true,
+	"{":     true,
+	"if":    true,
+	"then":  true,
+	"else":  true,
+	"elif":  true,
+	"while": true,
+	"until": true,
+	"do":    true,
+}
+
+// scanList scans the commands from s[i:] until the terminator term. It
+// returns the index after the terminator, or the context of the word at
+// the end of s if the terminator is not found.
+func scanList(s string, i int, term byte) (int, *wordContext) {
+	cmd := true
+	redir := 0 // 1: target of redirection, 2: here-document delimiter
+	paren := 0
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			i += 2
+		case c == '#':
+			// comment
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				return len(s), &wordContext{start: len(s)}
+			}
+			i += j
+		case c == term && paren == 0:
+			return i + 1, nil
+		case c == '<' || c == '>':
+			op := s[i : i+1]
+			for _, o := range []string{"<<-", "<<", "<&", "<>", ">>", ">&", ">|"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			i += len(op)
+			redir = 1
+			if strings.HasPrefix(op, "<<") {
+				redir = 2
+			}
+		case c == '(' || c == ')':
+			if c == '(' {
+				paren++
+			} else if paren > 0 {
+				paren--
+			}
+			cmd, redir = true, 0
+			i++
+		case c == ';' || c == '&' || c == '|' || c == '\n':
+			cmd, redir = true, 0
+			i++
+		default:
+			j, wc, quote := scanWord(s, i, term)
+			switch {
+			case wc != nil:
+				return j, wc
+			case j == len(s):
+				// the cursor is in the word
+				return j, newWordContext(s, i, cmd, redir, quote)
+			}
+			switch w := s[i:j]; {
+			case redir != 0:
+				redir = 0
+			case !cmd:
+			case strings.Trim(w, "0123456789") == "" && (s[j] == '<' || s[j] == '>'):
+				// I/O number
+			case keepCmdPos[w] || isAssign(w):
+			default:
+				cmd = false
+			}
+			i = j
+		}
+	}
+
+	wc := &wordContext{start: len(s)}
+	switch {
+	case redir == 1:
+		wc.kind = CompleteRedirection
+	case redir == 2:
+	case cmd:
+		wc.kind = CompleteCommand
+	default:
+		wc.kind = CompleteArgument
+	}
+	return len(s), wc
+}
+
+// newWordContext returns the context of the word which begins at s[i:]
+// and continues to the cursor.
+func newWordContext(s string, i int, cmd bool, redir int, quote byte) *wordContext {
+	wc := &wordContext{start: i}
+	w := s[i:]
+	if cmd && redir == 0 && isAssign(w) {
+		// value of the variable assignment
+		wc.start += strings.IndexByte(w, '=') + 1
+		w = s[wc.start:]
+		cmd = false
+	}
+	switch {
+	case redir == 2:
+		return wc
+	case strings.HasPrefix(w, "~") && !strings.Contains(w, "/") && quote == 0:
+		wc.kind = CompleteTilde
+		return wc
+	case redir == 1:
+		wc.kind = CompleteRedirection
+	case cmd:
+		wc.kind = CompleteCommand
+	default:
+		wc.kind = CompleteArgument
+	}
+	switch w[0] {
+	case '\'', '"':
+		wc.quote = w[0]
+	}
+	return wc
+}
+
+// isAssign reports whether the word is a variable assignment.
+func isAssign(w string) bool {
+	i := strings.IndexByte(w, '=')
+	if i <= 0 || ('0' <= w[0] && w[0] <= '9') {
+		return false
+	}
+	for j := range i {
+		if !isNameByte(w[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanWord scans the word from s[i:]. It returns the index of the end of
+// the word, or the context of the word at the end of s if the cursor is
+// in an expansion. It also returns the quote which is not closed at the
+// end of s.
+func scanWord(s string, i int, term byte) (int, *wordContext, byte) {
+	var quote byte
+	for i < len(s) {
+		switch c := s[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+			i++
+		case c == '\\':
+			i += 2
+		case c == term && quote == 0:
+			return i, nil, 0
+		case c == '\'' && quote == 0:
+			quote = '\''
+			i++
+		case c == '"':
+			if quote == '"' {
+				quote = 0
+			} else {
+				quote = '"'
+			}
+			i++
+		case c == '`':
+			j, wc := scanList(s, i+1, '`')
+			if wc != nil {
+				return j, wc, 0
+			}
+			i = j
+		case c == '$':
+			j, wc := scanDollar(s, i)
+			if wc != nil {
+				return j, wc, 0
+			}
+			i = j
+		case quote == 0 && term != '}' && strings.IndexByte(" \t\n;&|<>()", c) >= 0:
+			return i, nil, 0
+		default:
+			i++
+		}
+	}
+	return len(s), nil, quote
+}
+
+// scanDollar scans the expansion which begins with "$" at s[i:].
+func scanDollar(s string, i int) (int, *wordContext) {
+	i++
+	switch {
+	case i == len(s):
+		return i, &wordContext{
+			kind:  CompleteVariable,
+			start: i,
+		}
+	case strings.HasPrefix(s[i:], "(("):
+		// arithmetic expansion
+		n := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '(':
+				n++
+			case ')':
+				if n--; n == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return len(s), &wordContext{start: len(s)}
+	case s[i] == '(':
+		return scanList(s, i+1, ')')
+	case s[i] == '{':
+		j := i + 1
+		for j < len(s) && isNameByte(s[j]) {
+			j++
+		}
+		if j == len(s) {
+			return j, &wordContext{
+				kind:  CompleteVariable,
+				start: i + 1,
+				brace: true,
+			}
+		}
+		j, wc, _ := scanWord(s, j, '}')
+		switch {
+		case wc != nil:
+			return j, wc
+		case j == len(s):
+			return j, &wordContext{start: len(s)}
+		}
+		return j + 1, nil
+	case isNameByte(s[i]) && (s[i] < '0' || '9' < s[i]):
+		j := i
+		for j < len(s) && isNameByte(s[j]) {
+			j++
+		}
+		if j == len(s) {
+			return j, &wordContext{
+				kind:  CompleteVariable,
+				start: i,
+			}
+		}
+		return j, nil
+	case strings.IndexByte("@*#?-$!0123456789", s[i]) >= 0:
+		return i + 1, nil
+	}
+	return i, nil
+}
+
+// isNameByte reports whether c can be a part of a name. A byte of a
+// multibyte character is assumed to be a letter.
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('0' <= c && c <= '9') || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
@@ -0,0 +1,176 @@
+//
+// go.sh/interp :: complete_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var completeTests = []struct {
+	line       string
+	pos        int
+	kind       interp.CompletionKind
+	start      int
+	word       string
+	candidates []string
+}{
+	// command
+	{"ec", -1, interp.CompleteCommand, 0, "ec", []string{"echo"}},
+	{"echo", 2, interp.CompleteCommand, 0, "ec", []string{"echo"}},
+	{"go_sh_", -1, interp.CompleteCommand, 0, "go_sh_", []string{"go_sh_cmd", "go_sh_func"}},
+	{"geto", -1, interp.CompleteCommand, 0, "geto", []string{"getopts"}},
+	{"expo", -1, interp.CompleteCommand, 0, "expo", []string{"export"}},
+	{"hel", -1, interp.CompleteCommand, 0, "hel", []string{"helper"}},
+	{"", -1, interp.CompleteCommand, 0, "", nil},
+	{"true; ec", -1, interp.CompleteCommand, 6, "ec", []string{"echo"}},
+	{"true && ec", -1, interp.CompleteCommand, 8, "ec", []string{"echo"}},
+	{"true | ec", -1, interp.CompleteCommand, 7, "ec", []string{"echo"}},
+	{"true &\nec", -1, interp.CompleteCommand, 7, "ec", []string{"echo"}},
+	{"(ec", -1, interp.CompleteCommand, 1, "ec", []string{"echo"}},
+	{"{ ec", -1, interp.CompleteCommand, 2, "ec", []string{"echo"}},
+	{"if ! ec", -1, interp.CompleteCommand, 5, "ec", []string{"echo"}},
+	{"while true; do ec", -1, interp.CompleteCommand, 15, "ec", []string{"echo"}},
+	{"V=foo ec", -1, interp.CompleteCommand, 6, "ec", []string{"echo"}},
+	{"2>/dev/null ec", -1, interp.CompleteCommand, 12, "ec", []string{"echo"}},
+	{"echo $(ec", -1, interp.CompleteCommand, 7, "ec", []string{"echo"}},
+	{"echo \"$(ec", -1, interp.CompleteCommand, 8, "ec", []string{"echo"}},
+	{"echo `ec", -1, interp.CompleteCommand, 6, "ec", []string{"echo"}},
+	{"'ec", -1, interp.CompleteCommand, 0, "ec", []string{"'echo'"}},
+	// argument
+	{"echo f", -1, interp.CompleteArgument, 5, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"echo f f", 6, interp.CompleteArgument, 5, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"echo ", -1, interp.CompleteArgument, 5, "", []string{"fob/", "foo", `foo\ bar`}},
+	{`echo foo\ b`, -1, interp.CompleteArgument, 5, "foo b", []string{`foo\ bar`}},
+	{"echo 'foo ", -1, interp.CompleteArgument, 5, "foo ", []string{"'foo bar'"}},
+	{`echo "fo`, -1, interp.CompleteArgument, 5, "fo", []string{`"fob/`, `"foo"`, `"foo bar"`}},
+	{"echo fob/", -1, interp.CompleteArgument, 5, "fob/", []string{"fob/baz"}},
+	{"echo .h", -1, interp.CompleteArgument, 5, ".h", []string{".hidden"}},
+	{"echo ~/f", -1, interp.CompleteArgument, 5, "~/f", []string{"~/fob/", "~/foo", `~/foo\ bar`}},
+	{"echo $(echo foo) ec", -1, interp.CompleteArgument, 17, "ec", nil},
+	{"for i in f", -1, interp.CompleteArgument, 9, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"V=f", -1, interp.CompleteArgument, 2, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"echo $V/f", -1, interp.CompleteArgument, 5, "", nil},
+	// variable
+	{"echo $GO_SH_V", -1, interp.CompleteVariable, 6, "GO_SH_V", []string{"GO_SH_V1", "GO_SH_V2"}},
+	{"echo ${GO_SH_V", -1, interp.CompleteVariable, 7, "GO_SH_V", []string{"GO_SH_V1}", "GO_SH_V2}"}},
+	{"echo \"$GO_SH_V1", -1, interp.CompleteVariable, 7, "GO_SH_V1", []string{"GO_SH_V1"}},
+	{"echo foo$GO_SH_V2", -1, interp.CompleteVariable, 9, "GO_SH_V2", []string{"GO_SH_V2"}},
+	{"echo ${V:-$GO_SH_V2", -1, interp.CompleteVariable, 11, "GO_SH_V2", []string{"GO_SH_V2"}},
+	{"echo ${GO_SH_UNSET", -1, interp.CompleteVariable, 7, "GO_SH_UNSET", nil},
+	{"echo $GO_SH_UNSET", -1, interp.CompleteVariable, 6, "GO_SH_UNSET", nil},
+	// redirection
+	{"cat <f", -1, interp.CompleteRedirection, 5, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"cat 2> f", -1, interp.CompleteRedirection, 7, "f", []string{"fob/", "foo", `foo\ bar`}},
+	{"cat >>fo", -1, interp.CompleteRedirection, 6, "fo", []string{"fob/", "foo", `foo\ bar`}},
+	// tilde
+	{"echo ~", -1, interp.CompleteTilde, 5, "~", nil},
+	{"V=~", -1, interp.CompleteTilde, 2, "~", nil},
+	// none
+	{"cat <<EO", -1, interp.CompleteNone, 6, "EO", nil},
+	{"echo # f", -1, interp.CompleteNone, 8, "", nil},
+	{"echo $((1 + ", -1, interp.CompleteNone, 12, "", nil},
+	{"echo ${V:-f", -1, interp.CompleteNone, 11, "", nil},
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"foo", "foo bar", ".hidden", filepath.Join("fob", "baz")} {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := t.TempDir()
+	exe := "go_sh_cmd"
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(bin, exe), nil, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "go_sh_noexec.txt"), nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	env, _, _ := newTestEnv(t)
+	if err := env.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	env.Set("HOME", dir)
+	env.Set("PATH", bin)
+	env.Set("GO_SH_V1", "1")
+	env.Set("GO_SH_V2", "2")
+	if _, err := run(env, "go_sh_func() { :; }"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range completeTests {
+		pos := tt.pos
+		if pos < 0 {
+			pos = len(tt.line)
+		}
+		c := env.Complete(context.Background(), tt.line, pos)
+		if g, e := c.Kind, tt.kind; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.line, e, g)
+		}
+		if g, e := c.Start, tt.start; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.line, e, g)
+		}
+		if g, e := c.End, pos; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.line, e, g)
+		}
+		if g, e := c.Word, tt.word; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.line, e, g)
+		}
+		if tt.kind == interp.CompleteTilde || tt.line == "" {
+			continue
+		}
+		if g, e := c.Candidates, tt.candidates; !slices.Equal(g, e) {
+			t.Errorf("%q: expected %q, got %q", tt.line, e, g)
+		}
+	}
+}
+
+func TestCompleteExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires the executable bit")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "exe"), nil, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "exe.txt"), nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "exe.d"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	env, _, _ := newTestEnv(t)
+	if err := env.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	c := env.Complete(context.Background(), "./ex", 4)
+	if g, e := c.Candidates, []string{"./exe", "./exe.d/"}; !slices.Equal(g, e) {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	c = env.Complete(context.Background(), "echo ./ex", 9)
+	if g, e := c.Candidates, []string{"./exe", "./exe.d/", "./exe.txt"}; !slices.Equal(g, e) {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
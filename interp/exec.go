@@ -0,0 +1,921 @@
+//
+// go.sh/interp :: exec.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/pattern"
+)
+
+// Run executes a command, and returns its exit status.
+//
+// The actions of the traps for the received signals are executed after
+// the pipeline being executed completes. When the shell exits, or the
+// context is done, the action of the EXIT trap is executed unless the
+// cause of the context is ErrInterrupted. The error caused by the context
+// is returned as a *ContextError.
+func (env *ExecEnv) Run(ctx context.Context, cmd ast.Command) (status int, err error) {
+	status, err = env.runCommand(ctx, cmd)
+	var ee *ExitError
+	switch {
+	case errors.As(err, &ee):
+		status, err = env.exitTrap(ctx, status, err)
+		env.stopTraps()
+	case isContextError(err):
+		if !errors.Is(context.Cause(ctx), ErrInterrupted) {
+			status, err = env.exitTrap(ctx, status, err)
+		}
+		var cerr *ContextError
+		if !errors.As(err, &cerr) {
+			err = &ContextError{Err: err}
+		}
+	}
+	return
+}
+
+// runCommand executes a command.
+func (env *ExecEnv) runCommand(ctx context.Context, cmd ast.Command) (status int, err error) {
+	switch cmd := cmd.(type) {
+	case ast.List:
+		for _, ao := range cmd {
+			if status, err = env.runAndOrList(ctx, ao); err != nil {
+				break
+			}
+		}
+	case *ast.AndOrList:
+		status, err = env.runAndOrList(ctx, cmd)
+	case *ast.Pipeline:
+		status, err = env.runAndOr(ctx, &ast.AndOrList{Pipeline: cmd})
+	case *ast.Cmd:
+		status, err = env.runAndOr(ctx, &ast.AndOrList{Pipeline: &ast.Pipeline{Cmd: cmd}})
+	default:
+		err = fmt.Errorf("unknown command: %T", cmd)
+	}
+	return
+}
+
+// runAndOrList executes an AND-OR list. If it is terminated by "&", it
+// is executed asynchronously.
+func (env *ExecEnv) runAndOrList(ctx context.Context, cmd *ast.AndOrList) (int, error) {
+	if cmd.Sep == "&" {
+		return env.runAsync(ctx, cmd)
+	}
+	return env.runAndOr(ctx, cmd)
+}
+
+// runAndOr executes the pipelines of an AND-OR list.
+func (env *ExecEnv) runAndOr(ctx context.Context, cmd *ast.AndOrList) (status int, err error) {
+	if status, err = env.runElem(ctx, cmd.Pipeline, len(cmd.List) > 0); err != nil {
+		return
+	}
+	for i, ao := range cmd.List {
+		switch {
+		case ao.Op == "&&" && status != 0:
+		case ao.Op == "||" && status == 0:
+		default:
+			if status, err = env.runElem(ctx, ao.Pipeline, i < len(cmd.List)-1); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// runElem executes a pipeline of an AND-OR list, and the actions of the
+// traps after it. If cond is true, the pipeline is not the last one of
+// the list.
+func (env *ExecEnv) runElem(ctx context.Context, cmd *ast.Pipeline, cond bool) (status int, err error) {
+	cond = cond || !cmd.Bang.IsZero()
+	env.errIgnored = false
+	env.errTrapped = false
+	if cond {
+		env.cond++
+	}
+	status, err = env.runJob(ctx, cmd)
+	if cond {
+		env.cond--
+	}
+	if err != nil {
+		return
+	}
+	if err = env.runTraps(ctx); err == nil {
+		err = env.errTrap(ctx, status, cond)
+	}
+	return
+}
+
+// runPipeline executes a pipeline.
+func (env *ExecEnv) runPipeline(ctx context.Context, cmd *ast.Pipeline) (status int, err error) {
+	if len(cmd.List) == 0 {
+		status, err = env.runCmd(ctx, cmd.Cmd)
+	} else {
+		cmds := make([]*ast.Cmd, len(cmd.List)+1)
+		cmds[0] = cmd.Cmd
+		for i, p := range cmd.List {
+			cmds[i+1] = p.Cmd
+		}
+		status, err = env.pipe(ctx, cmds)
+	}
+	if err != nil {
+		return
+	}
+	if !cmd.Bang.IsZero() {
+		if status == 0 {
+			status = 1
+		} else {
+			status = 0
+		}
+	}
+	env.status = status
+	return
+}
+
+// pipe executes each command concurrently in a subshell environment
+// with the standard output of each command connected by a pipe to the
+// standard input of the next command, and returns the exit status of
+// the last command.
+func (env *ExecEnv) pipe(ctx context.Context, cmds []*ast.Cmd) (int, error) {
+	envs := make([]*ExecEnv, len(cmds))
+	var stderr io.Writer = env.Stderr
+	if _, ok := stderr.(*os.File); !ok && stderr != nil {
+		stderr = &syncWriter{w: stderr}
+	}
+	// pipes
+	r := make([]*os.File, len(cmds))
+	w := make([]*os.File, len(cmds))
+	for i := range len(cmds) - 1 {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			for j := range i {
+				r[j+1].Close()
+				w[j].Close()
+			}
+			return 1, err
+		}
+		r[i+1] = pr
+		w[i] = pw
+	}
+	for i := range cmds {
+		envs[i] = env.Subshell()
+		if i > 0 {
+			envs[i].Stdin = r[i]
+		}
+		if i < len(cmds)-1 {
+			envs[i].Stdout = &pipeWriter{f: w[i]}
+			envs[i].upstream = true
+		} else {
+			envs[i].upstream = env.upstream
+		}
+		envs[i].Stderr = stderr
+	}
+
+	status := make([]int, len(cmds))
+	errs := make([]error, len(cmds))
+	var wg sync.WaitGroup
+	wg.Add(len(cmds))
+	for i, cmd := range cmds {
+		go func(sub *ExecEnv) {
+			defer wg.Done()
+
+			status[i], errs[i] = sub.runCmd(ctx, cmd)
+			status[i], errs[i] = sub.exit(ctx, status[i], errs[i])
+			// close the read end after the reader was finished, then
+			// the writer will get EPIPE
+			if r[i] != nil {
+				r[i].Close()
+			}
+			// close the write end after the writer was finished, then
+			// the reader will get EOF
+			if w[i] != nil {
+				w[i].Close()
+			}
+		}(envs[i])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return 1, err
+		}
+	}
+	return status[len(status)-1], nil
+}
+
+// pipeWriter is the write end of a pipe which records whether the read
+// end was closed.
+type pipeWriter struct {
+	f      *os.File
+	broken atomic.Bool
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	if err != nil && isBrokenPipe(err) {
+		w.broken.Store(true)
+	}
+	return n, err
+}
+
+// osFile returns the open file of v.
+func osFile(v any) (*os.File, bool) {
+	switch v := v.(type) {
+	case *os.File:
+		return v, true
+	case *pipeWriter:
+		return v.f, true
+	}
+	return nil, false
+}
+
+// exit converts an error which causes a subshell environment to exit
+// into its exit status, and executes the action of the EXIT trap.
+func (env *ExecEnv) exit(ctx context.Context, status int, err error) (int, error) {
+	var lc *loopCtl
+	var rc *returnCtl
+	var ee *ExitError
+	switch {
+	case err == nil:
+	case isContextError(err):
+	case errors.As(err, &lc):
+		err = nil
+	case errors.As(err, &rc):
+		status, err = rc.status, nil
+	case errors.As(err, &ee):
+		status, err = ee.Status, nil
+	default:
+		fmt.Fprintln(env.Stderr, err)
+		status, err = 2, nil
+	}
+	defer env.closeFiles()
+	defer env.stopTraps()
+	return env.exitTrap(ctx, status, err)
+}
+
+// exitTrap executes the action of the EXIT trap once. If the action
+// exits the shell, its exit status takes precedence.
+func (env *ExecEnv) exitTrap(ctx context.Context, status int, err error) (int, error) {
+	action, ok := env.traps["EXIT"]
+	delete(env.traps, "EXIT")
+	if !ok || action == "" {
+		return status, err
+	}
+
+	env.status = status
+	_, terr := env.source(context.WithoutCancel(ctx), env.Args[0], strings.NewReader(action))
+	var ee *ExitError
+	switch {
+	case errors.As(terr, &ee):
+		status = ee.Status
+		if errors.As(err, new(*ExitError)) {
+			err = ee
+		}
+	case terr != nil && !isContextError(terr):
+		fmt.Fprintln(env.Stderr, terr)
+	}
+	return status, err
+}
+
+// isContextError reports whether err is caused by the context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// ContextError represents an error when the execution was stopped by the
+// context.
+type ContextError struct {
+	Err error
+}
+
+func (e *ContextError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the deadline of the context was exceeded
+// rather than the context was canceled.
+func (e *ContextError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// runList executes a list of commands.
+func (env *ExecEnv) runList(ctx context.Context, cmds []ast.Command) (status int, err error) {
+	for _, cmd := range cmds {
+		if status, err = env.runCommand(ctx, cmd); err != nil {
+			break
+		}
+	}
+	return
+}
+
+// runCond executes a list of commands as the condition of a compound
+// command.
+func (env *ExecEnv) runCond(ctx context.Context, cmds []ast.Command) (int, error) {
+	env.cond++
+	defer func() { env.cond-- }()
+	return env.runList(ctx, cmds)
+}
+
+// runCmd executes a command.
+func (env *ExecEnv) runCmd(ctx context.Context, cmd *ast.Cmd) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 1, err
+	}
+	if pos := cmd.Pos(); !pos.IsZero() {
+		env.lineno = pos.Line()
+	}
+	if x, ok := cmd.Expr.(*ast.SimpleCmd); ok {
+		status, err := env.runSimpleCmd(ctx, x, cmd.Redirs)
+		// the failures of the commands executed by a simple command,
+		// such as a function, do not exempt it
+		env.errIgnored = false
+		return status, err
+	}
+
+	restore, err := env.redirect(ctx, cmd.Redirs, false)
+	if err != nil {
+		return 1, err
+	}
+	defer restore()
+
+	switch x := cmd.Expr.(type) {
+	case *ast.Subshell:
+		return env.runSubshell(ctx, x)
+	case *ast.Group:
+		return env.runList(ctx, x.List)
+	case *ast.ArithEval:
+		return env.runArithEval(ctx, x)
+	case *ast.ForClause:
+		return env.runFor(ctx, x)
+	case *ast.CaseClause:
+		return env.runCase(ctx, x)
+	case *ast.IfClause:
+		return env.runIf(ctx, x)
+	case *ast.WhileClause:
+		return env.runLoop(ctx, x.Cond, x.List, false)
+	case *ast.UntilClause:
+		return env.runLoop(ctx, x.Cond, x.List, true)
+	case *ast.FuncDef:
+		env.funcs.set(x.Name.Value, x)
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%T is not supported", cmd.Expr)
+}
+
+// runSubshell executes commands in a subshell environment.
+func (env *ExecEnv) runSubshell(ctx context.Context, x *ast.Subshell) (int, error) {
+	sub := env.Subshell()
+	status, err := sub.runList(ctx, x.List)
+	return sub.exit(ctx, status, err)
+}
+
+// runArithEval evaluates an arithmetic expression.
+func (env *ExecEnv) runArithEval(ctx context.Context, x *ast.ArithEval) (int, error) {
+	fields, err := env.ExpandContext(ctx, ast.Word{&ast.ArithExp{Expr: x.Expr}}, Literal)
+	if err != nil {
+		return 1, err
+	}
+	if fields[0] == "0" {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// runFor executes a for loop.
+func (env *ExecEnv) runFor(ctx context.Context, x *ast.ForClause) (status int, err error) {
+	var items []string
+	if x.In.IsZero() {
+		items = slices.Clone(env.Args[1:])
+	} else {
+		for _, w := range x.Items {
+			fields, err := env.ExpandContext(ctx, w, 0)
+			if err != nil {
+				return 1, err
+			}
+			items = append(items, fields...)
+		}
+	}
+
+	env.loop++
+	defer func() { env.loop-- }()
+	for _, s := range items {
+		if err = env.assign(x.Name.Value, s); err != nil {
+			return 1, err
+		}
+		var brk bool
+		status, err = env.runList(ctx, x.List)
+		if brk, err = env.loopControl(err); brk || err != nil {
+			break
+		}
+	}
+	return
+}
+
+// runCase executes a case conditional construct.
+func (env *ExecEnv) runCase(ctx context.Context, x *ast.CaseClause) (int, error) {
+	fields, err := env.ExpandContext(ctx, x.Word, Literal)
+	if err != nil {
+		return 1, err
+	}
+	s := fields[0]
+	for i, ci := range x.Items {
+		for _, w := range ci.Patterns {
+			fields, err := env.ExpandContext(ctx, w, Pattern)
+			if err != nil {
+				return 1, err
+			}
+			switch m, err := pattern.Match(fields, pattern.Suffix|pattern.Largest, s); {
+			case err == nil && m == s:
+				status := 0
+				for _, ci := range x.Items[i:] {
+					if status, err = env.runList(ctx, ci.List); err != nil || ci.Fallthrough.IsZero() {
+						break
+					}
+				}
+				return status, err
+			case err != nil && err != pattern.NoMatch:
+				return 1, err
+			}
+		}
+	}
+	return 0, nil
+}
+
+// runIf executes an if conditional construct.
+func (env *ExecEnv) runIf(ctx context.Context, x *ast.IfClause) (int, error) {
+	switch status, err := env.runCond(ctx, x.Cond); {
+	case err != nil:
+		return status, err
+	case status == 0:
+		return env.runList(ctx, x.List)
+	}
+	for _, e := range x.Else {
+		switch e := e.(type) {
+		case *ast.ElifClause:
+			switch status, err := env.runCond(ctx, e.Cond); {
+			case err != nil:
+				return status, err
+			case status == 0:
+				return env.runList(ctx, e.List)
+			}
+		case *ast.ElseClause:
+			return env.runList(ctx, e.List)
+		}
+	}
+	return 0, nil
+}
+
+// runLoop executes a while loop or an until loop.
+func (env *ExecEnv) runLoop(ctx context.Context, cond, body []ast.Command, until bool) (status int, err error) {
+	env.loop++
+	defer func() { env.loop-- }()
+	for {
+		var rv int
+		var brk bool
+		rv, err = env.runCond(ctx, cond)
+		if brk, err = env.loopControl(err); brk || err != nil {
+			return
+		}
+		if (rv == 0) == until {
+			return
+		}
+		status, err = env.runList(ctx, body)
+		if brk, err = env.loopControl(err); brk || err != nil {
+			return
+		}
+	}
+}
+
+// loopControl reports whether the innermost loop should be terminated
+// by the error. It returns the error as is if it should be propagated to
+// the enclosing loop or the caller.
+func (env *ExecEnv) loopControl(err error) (bool, error) {
+	var lc *loopCtl
+	switch {
+	case err == nil:
+		return false, nil
+	case !errors.As(err, &lc):
+		return false, err
+	case lc.n > 1:
+		lc.n--
+		return false, lc
+	}
+	return !lc.cont, nil
+}
+
+// loopCtl represents a break or continue of enclosing loops.
+type loopCtl struct {
+	n    int
+	cont bool
+}
+
+func (lc *loopCtl) Error() string {
+	if lc.cont {
+		return "continue"
+	}
+	return "break"
+}
+
+// runSimpleCmd executes a simple command.
+func (env *ExecEnv) runSimpleCmd(ctx context.Context, cmd *ast.SimpleCmd, redirs []*ast.Redir) (status int, err error) {
+	// expand words
+	env.substStatus = 0
+	stderr := env.Stderr
+	var args []string
+	for _, w := range cmd.Args {
+		fields, err := env.ExpandContext(ctx, w, 0)
+		if err != nil {
+			return 1, err
+		}
+		args = append(args, fields...)
+	}
+	// redirections
+	var spBuiltin Builtin
+	if len(args) > 0 {
+		spBuiltin = spBuiltins[args[0]]
+	}
+	sp := spBuiltin != nil
+	// the redirections of exec without a command are persistent
+	restore, err := env.redirect(ctx, redirs, sp && len(args) == 1 && args[0] == "exec")
+	if err != nil {
+		var rerr RedirError
+		if sp || !errors.As(err, &rerr) {
+			return 1, err
+		}
+		fmt.Fprintln(env.Stderr, err)
+		return 1, nil
+	}
+	defer restore()
+	// variable assignments
+	vars := make([]Var, len(cmd.Assigns))
+	var assigns []string
+	for i, a := range cmd.Assigns {
+		fields, err := env.ExpandContext(ctx, a.Value, Assign|Literal)
+		if err != nil {
+			return 1, err
+		}
+		assigns = append(assigns, a.Name.Value+"="+fields[0])
+		if len(args) == 0 || sp {
+			if err := env.assign(a.Name.Value, fields[0]); err != nil {
+				return 1, err
+			}
+			continue
+		}
+		if v, _ := env.Get(a.Name.Value); v.ReadOnly {
+			return 1, fmt.Errorf("%v: %w", a.Name.Value, ErrReadOnly)
+		}
+		vars[i] = Var{
+			Name:   a.Name.Value,
+			Value:  fields[0],
+			Export: true,
+		}
+	}
+	// trace
+	if !env.tracing {
+		if env.Opts&XTrace != 0 && len(assigns)+len(args) > 0 {
+			env.xtrace(ctx, stderr, assigns, args)
+		}
+		if env.Trace != nil {
+			ev := TraceEvent{
+				Pos:     cmd.Pos(),
+				Args:    args,
+				Assigns: assigns,
+			}
+			start := time.Now()
+			defer func() {
+				ev.Status = status
+				ev.Duration = time.Since(start)
+				env.Trace(ev)
+			}()
+		}
+	}
+	switch {
+	case len(args) == 0:
+		return env.substStatus, nil
+	case sp:
+		env.start(0)
+		return env.sigpipe(spBuiltin(ctx, env, args))
+	}
+	if fn, ok := env.funcs.m[args[0]]; ok {
+		env.start(0)
+		return env.call(ctx, fn, args, vars)
+	}
+	if fn, ok := env.Builtins[args[0]]; ok {
+		env.start(0)
+		defer env.setVars(vars)()
+		return env.sigpipe(fn(ctx, env, args))
+	}
+	return env.exec(ctx, args, vars)
+}
+
+// sigpipe exits the subshell environment of a command of the pipeline
+// with the exit status of SIGPIPE, if the built-in utility wrote to the
+// next command which had exited, as if it was killed by the signal.
+func (env *ExecEnv) sigpipe(status int, err error) (int, error) {
+	if w, ok := env.Stdout.(*pipeWriter); ok && err == nil && w.broken.Load() {
+		status = 128 + int(syscall.SIGPIPE)
+		err = &ExitError{Status: status}
+	}
+	return status, err
+}
+
+// call executes a function.
+func (env *ExecEnv) call(ctx context.Context, fn *ast.FuncDef, args []string, vars []Var) (status int, err error) {
+	if env.MaxCallDepth > 0 && env.depth >= env.MaxCallDepth {
+		return 1, fmt.Errorf("%v: %w (%v)", args[0], ErrCallDepth, env.MaxCallDepth)
+	}
+
+	restore := env.setVars(vars)
+	// positional parameters
+	posParams := env.Args
+	env.Args = append([]string{env.Args[0]}, args[1:]...)
+	loop := env.loop
+	env.loop = 0
+	env.depth++
+	defer func() {
+		env.depth--
+		env.loop = loop
+		env.Args = posParams
+		restore()
+	}()
+
+	status, err = env.runCommand(ctx, fn.Body)
+	var rc *returnCtl
+	if errors.As(err, &rc) {
+		return rc.status, nil
+	}
+	return
+}
+
+// setVars sets the variables temporarily, and returns a function to
+// restore them.
+func (env *ExecEnv) setVars(vars []Var) (restore func()) {
+	type saved struct {
+		v   Var
+		set bool
+	}
+	stack := make([]saved, len(vars))
+	for i, v := range vars {
+		k := env.keyFor(v.Name)
+		stack[i].v, stack[i].set = env.vars.m[k]
+		env.vars.set(k, v)
+	}
+	return func() {
+		for i := len(vars) - 1; i >= 0; i-- {
+			k := env.keyFor(vars[i].Name)
+			if stack[i].set {
+				env.vars.set(k, stack[i].v)
+			} else {
+				env.vars.delete(k)
+			}
+		}
+	}
+}
+
+// returnCtl represents a return from a function.
+type returnCtl struct {
+	status int
+}
+
+func (rc *returnCtl) Error() string {
+	return "return"
+}
+
+// exec executes an external utility.
+func (env *ExecEnv) exec(ctx context.Context, args []string, vars []Var) (int, error) {
+	path, err := env.lookPath(args[0])
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "%v: %v\n", args[0], err)
+		if errors.Is(err, os.ErrPermission) {
+			return 126, nil
+		}
+		return 127, nil
+	}
+
+	return env.run(ctx, path, args, vars)
+}
+
+// run executes the utility named by the path.
+func (env *ExecEnv) run(ctx context.Context, path string, args []string, vars []Var) (int, error) {
+	cmd := &Cmd{
+		Path:   path,
+		Args:   args,
+		Env:    env.environ(vars...),
+		Dir:    env.getwd(),
+		Stdin:  env.Stdin,
+		Stdout: env.Stdout,
+		Stderr: env.Stderr,
+	}
+	if f, ok := osFile(cmd.Stdout); ok {
+		cmd.Stdout = f
+	}
+	if f, ok := osFile(cmd.Stderr); ok {
+		cmd.Stderr = f
+	}
+	if runtime.GOOS != "windows" {
+		cmd.ExtraFiles = env.extraFiles()
+	}
+	if env.started != nil {
+		cmd.started = env.start
+	}
+	if env.Opts&Monitor != 0 {
+		cmd.job = env.job
+		cmd.upstream = env.upstream
+	}
+	h := env.ExecHandler
+	if h == nil {
+		h = DefaultExecHandler
+	}
+	return h(ctx, cmd)
+}
+
+// waitDelay is the time to wait for the I/O of the utility after the
+// context is done.
+const waitDelay = 1 * time.Second
+
+// ExecHandler is the type of the function which executes an external
+// utility. It returns the exit status of the utility, and a non-nil error
+// is returned from ExecEnv.Run as is.
+type ExecHandler func(ctx context.Context, cmd *Cmd) (int, error)
+
+// Cmd represents an external utility being executed.
+type Cmd struct {
+	// Path is the path of the utility found by the PATH variable.
+	Path string
+
+	// Args holds the expanded command line arguments, including the
+	// command name as Args[0].
+	Args []string
+
+	// Env is the environment of the utility in the form "key=value".
+	Env []string
+
+	// Dir is the working directory of the utility.
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ExtraFiles specifies the open files inherited by the utility in
+	// addition to the standard input, output, and error. The entry i
+	// becomes the file descriptor 3+i.
+	ExtraFiles []*os.File
+
+	// started is called with the process ID after the utility was
+	// started.
+	started func(pid int)
+	// job is the job which the utility belongs to if the Monitor option
+	// is set.
+	job *job
+	// upstream reports whether the standard output of the utility is
+	// connected to the next command of the pipeline.
+	upstream bool
+}
+
+// DefaultExecHandler executes the utility by the os/exec package.
+//
+// If the context can be canceled, the utility is started in a new
+// process group on Unix, and the whole process group is killed when the
+// context is done. If the Monitor option is set, the utility is started
+// in the process group of the job instead.
+func DefaultExecHandler(ctx context.Context, cmd *Cmd) (int, error) {
+	c := exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
+	c.Args[0] = cmd.Args[0]
+	c.Env = cmd.Env
+	c.Dir = cmd.Dir
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	c.ExtraFiles = cmd.ExtraFiles
+	if cmd.job != nil {
+		return cmd.job.exec(ctx, c, cmd)
+	}
+	if ctx.Done() != nil {
+		setpgid(c)
+		c.WaitDelay = waitDelay
+	}
+	err := c.Start()
+	if err == nil {
+		if cmd.started != nil {
+			cmd.started(c.Process.Pid)
+		}
+		err = c.Wait()
+	}
+	return execStatus(ctx, cmd, err)
+}
+
+// execStatus converts the error returned from exec.Cmd into the exit
+// status of the utility.
+func execStatus(ctx context.Context, cmd *Cmd, err error) (int, error) {
+	switch {
+	case err == nil:
+		return 0, nil
+	case ctx.Err() != nil:
+		return 1, ctx.Err()
+	default:
+		var eerr *exec.ExitError
+		if errors.As(err, &eerr) {
+			return exitStatus(eerr.ProcessState), nil
+		}
+		if cmd.Stderr != nil {
+			fmt.Fprintf(cmd.Stderr, "%v: %v\n", cmd.Args[0], err)
+		}
+		return 126, nil
+	}
+}
+
+// lookPath searches for an executable named by the name in the
+// directories named by the PATH variable.
+func (env *ExecEnv) lookPath(name string) (string, error) {
+	v, _ := env.Get("PATH")
+	return env.search(v.Value, name, env.findExecutable)
+}
+
+// search searches for a file named by the name in the directories of the
+// path.
+func (env *ExecEnv) search(path, name string, find func(string) (string, error)) (string, error) {
+	if strings.ContainsAny(name, pathSeps) {
+		return find(name)
+	}
+	var rv error = ErrNotFound
+	for _, dir := range filepath.SplitList(path) {
+		p := filepath.Join(dir, name)
+		if !strings.ContainsAny(p, pathSeps) {
+			p = "." + string(filepath.Separator) + p
+		}
+		switch p, err := find(p); {
+		case err == nil:
+			return p, nil
+		case errors.Is(err, os.ErrPermission):
+			rv = err
+		}
+	}
+	return "", rv
+}
+
+// environ returns a copy of strings representing the environment for
+// an external utility.
+func (env *ExecEnv) environ(vars ...Var) []string {
+	m := make(map[string]Var)
+	for k, v := range env.vars.m {
+		if v.Export && !v.unset {
+			m[k] = v
+		}
+	}
+	for _, v := range vars {
+		m[env.keyFor(v.Name)] = v
+	}
+	environ := make([]string, 0, len(m))
+	for _, v := range m {
+		environ = append(environ, v.Name+"="+v.Value)
+	}
+	slices.Sort(environ)
+	return environ
+}
+
+var (
+	// ErrNotFound indicates that the utility is not found.
+	ErrNotFound = errors.New("not found")
+	// ErrCallDepth indicates that the depth of nested function calls
+	// exceeds ExecEnv.MaxCallDepth.
+	ErrCallDepth = errors.New("maximum function call depth exceeded")
+	// ErrInterrupted is the cause of the context which indicates that
+	// the commands were interrupted by the user of an interactive shell
+	// rather than the shell is terminated.
+	ErrInterrupted = errors.New("interrupted")
+)
+
+// syncWriter serializes writes to the underlying io.Writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
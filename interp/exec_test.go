@@ -0,0 +1,626 @@
+//
+// go.sh/interp :: exec_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/interp"
+	"github.com/hattya/go.sh/parser"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_SH_HELPER") != "" {
+		os.Exit(helper(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// helper implements tiny utilities for testing.
+func helper(args []string) int {
+	switch args[0] {
+	case "cat":
+		if _, err := io.Copy(os.Stdout, os.Stdin); err != nil {
+			return 1
+		}
+	case "echo":
+		fmt.Println(strings.Join(args[1:], " "))
+	case "echo2":
+		fmt.Fprintln(os.Stderr, strings.Join(args[1:], " "))
+	case "exit":
+		n, _ := strconv.Atoi(args[1])
+		return n
+	case "kill":
+		n, _ := strconv.Atoi(args[1])
+		p, _ := os.FindProcess(os.Getpid())
+		if err := p.Signal(syscall.Signal(n)); err != nil {
+			return 1
+		}
+	case "pwd":
+		wd, err := os.Getwd()
+		if err != nil {
+			return 1
+		}
+		fmt.Println(wd)
+	case "sleep":
+		n, _ := strconv.Atoi(args[1])
+		time.Sleep(time.Duration(n) * time.Millisecond)
+	case "getenv":
+		for _, k := range args[1:] {
+			if v, ok := os.LookupEnv(k); ok {
+				fmt.Printf("%v=%v\n", k, v)
+			} else {
+				fmt.Printf("%v is unset\n", k)
+			}
+		}
+	case "yes":
+		for {
+			if _, err := fmt.Println("y"); err != nil {
+				return 1
+			}
+		}
+	case "wc":
+		n := 0
+		for s := bufio.NewScanner(os.Stdin); s.Scan(); {
+			n++
+		}
+		fmt.Println(n)
+	default:
+		return 127
+	}
+	return 0
+}
+
+var runTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	{"helper echo foo", "foo\n", 0},
+	{"helper echo foo; helper echo bar", "foo\nbar\n", 0},
+	{"helper exit 0", "", 0},
+	{"helper exit 1", "", 1},
+	{"helper exit 255", "", 255},
+	{"! helper exit 0", "", 1},
+	{"! helper exit 1", "", 0},
+	// AND-OR list
+	{"helper exit 0 && helper echo foo", "foo\n", 0},
+	{"helper exit 1 && helper echo foo", "", 1},
+	{"helper exit 0 || helper echo foo", "", 0},
+	{"helper exit 1 || helper echo foo", "foo\n", 0},
+	{"helper exit 1 && helper echo foo || helper echo bar", "bar\n", 0},
+	{"helper exit 0 || helper echo foo && helper echo bar", "bar\n", 0},
+	// asynchronous list
+	{"helper exit 3 & wait $!", "", 3},
+	{"helper sleep 50 & helper echo foo; wait; helper echo bar", "foo\nbar\n", 0},
+	{"helper exit 3 & X=$!; helper exit 4 & wait $X", "", 3},
+	{"helper exit 3 & helper exit 4 & wait", "", 0},
+	{"helper exit 0 && helper exit 3 & wait $!", "", 3},
+	{"helper echo foo | helper exit 3 & wait $!", "", 3},
+	{"{ helper exit 3; } & wait $!", "", 3},
+	{"f() { return 3; }; f & wait $!", "", 3},
+	{"FOO=foo & helper echo ${FOO-unset}", "unset\n", 0},
+	{"for i in 1 2 3; do helper exit $i & done; wait $!", "", 3},
+	{"helper exit 3 & X=$!; wait $X; wait $X", "", 127},
+	{"helper echo ${!-unset}", "unset\n", 0},
+	// pipeline
+	{"helper echo foo | helper cat", "foo\n", 0},
+	{"helper echo foo | helper cat | helper cat", "foo\n", 0},
+	{"helper echo foo | helper wc", "1\n", 0},
+	{"helper echo foo | helper exit 3", "", 3},
+	{"helper exit 3 | helper echo foo", "foo\n", 0},
+	{"! helper echo foo | helper exit 3", "", 0},
+	{"! helper exit 3 | helper exit 0", "", 1},
+	{"helper yes | helper exit 0", "", 0},
+	{"helper echo2 foo | helper wc", "0\n", 0},
+	{"FOO=foo | helper exit 0; helper echo ${FOO-unset}", "unset\n", 0},
+	// exit status
+	{"helper exit 3; helper echo $?", "3\n", 0},
+	{"! helper exit 3; helper echo $?", "0\n", 0},
+	{"helper exit 3 || helper echo $?", "3\n", 0},
+	// LINENO
+	{"helper echo $LINENO\nhelper echo $LINENO", "1\n2\n", 0},
+	{"f() {\n\thelper echo $LINENO\n}\n\nf", "2\n", 0},
+	{"for i in 1; do\n\thelper echo $LINENO\ndone", "2\n", 0},
+	{"(\n\thelper echo $LINENO\n)", "2\n", 0},
+	{"X=$(\n\thelper echo $LINENO\n); helper echo $X $LINENO", "2 3\n", 0},
+	{"LINENO=0; helper echo $LINENO", "1\n", 0},
+	// variable assignments
+	{"FOO=foo; helper echo $FOO", "foo\n", 0},
+	{"FOO=foo BAR=$FOO; helper echo $BAR", "foo\n", 0},
+	{"FOO=foo helper getenv FOO", "FOO=foo\n", 0},
+	{"FOO=foo; helper getenv FOO", "FOO is unset\n", 0},
+	{"FOO=foo helper exit 0; helper echo ${FOO-unset}", "unset\n", 0},
+	// subshell
+	{"(helper echo foo)", "foo\n", 0},
+	{"(helper exit 3)", "", 3},
+	{"(FOO=foo); helper echo ${FOO-unset}", "unset\n", 0},
+	{"(helper echo foo; helper echo bar) | helper wc", "2\n", 0},
+	// grouping command
+	{"{ helper echo foo; }", "foo\n", 0},
+	{"{ helper exit 3; }", "", 3},
+	{"{ FOO=foo; }; helper echo ${FOO-unset}", "foo\n", 0},
+	{"{ helper echo foo; helper echo bar; } | helper wc", "2\n", 0},
+	// arithmetic evaluation
+	{"((1 + 1))", "", 0},
+	{"((1 - 1))", "", 1},
+	{"((X = 1)); helper echo $X", "1\n", 0},
+	// for loop
+	{"for i in 1 2 3; do helper echo $i; done", "1\n2\n3\n", 0},
+	{"for i in; do helper echo $i; done", "", 0},
+	{"for i in 1 2 3; do helper exit $i; done", "", 3},
+	{"X='1 2'; for i in $X '3 4'; do helper echo $i; done", "1\n2\n3 4\n", 0},
+	{"for i in 1 2 3; do helper exit 0; done; helper echo $i", "3\n", 0},
+	// case conditional construct
+	{"case foo in foo) helper echo foo;; esac", "foo\n", 0},
+	{"case foo in bar) helper echo bar;; foo) helper echo foo;; esac", "foo\n", 0},
+	{"case foo in bar | foo) helper echo foo;; esac", "foo\n", 0},
+	{"case foo in f*) helper echo foo;; *) helper echo bar;; esac", "foo\n", 0},
+	{"case foo in f) helper echo foo;; esac", "", 0},
+	{"case foo in fo?) helper exit 3;; esac", "", 3},
+	{"case foo in 'f*') helper echo foo;; *) helper echo bar;; esac", "bar\n", 0},
+	{"X='f*'; case foo in $X) helper echo foo;; esac", "foo\n", 0},
+	{"X='f*'; case foo in \"$X\") helper echo foo;; *) helper echo bar;; esac", "bar\n", 0},
+	{"X=foo; case $X in foo) helper echo foo;; esac", "foo\n", 0},
+	{"case foo in foo) helper echo foo;& bar) helper echo bar;; baz) helper echo baz;; esac", "foo\nbar\n", 0},
+	{"case foo in foo) helper echo foo;& bar) helper echo bar;& esac", "foo\nbar\n", 0},
+	{"case '' in '') helper echo empty;; esac", "empty\n", 0},
+	// if conditional construct
+	{"if helper exit 0; then helper echo foo; fi", "foo\n", 0},
+	{"if helper exit 1; then helper echo foo; fi", "", 0},
+	{"if helper exit 1; then helper echo foo; else helper echo bar; fi", "bar\n", 0},
+	{"if helper exit 1; then helper echo foo; elif helper exit 0; then helper echo bar; fi", "bar\n", 0},
+	{"if helper exit 1; then helper echo foo; elif helper exit 1; then helper echo bar; else helper exit 3; fi", "", 3},
+	{"if helper exit 0; then helper exit 3; fi", "", 3},
+	// while loop
+	{"X=; while ((${#X} < 3)); do X=x$X; helper echo $X; done", "x\nxx\nxxx\n", 0},
+	{"while helper exit 1; do helper echo foo; done", "", 0},
+	{"X=; while ((${#X} < 3)); do X=x$X; helper exit ${#X}; done", "", 3},
+	// until loop
+	{"X=; until ((${#X} == 3)); do X=x$X; helper echo $X; done", "x\nxx\nxxx\n", 0},
+	{"until helper exit 0; do helper echo foo; done", "", 0},
+	// break
+	{"for i in 1 2 3; do helper echo $i; break; done", "1\n", 0},
+	{"for i in 1 2; do for j in 1 2; do helper echo $i$j; break; done; done", "11\n21\n", 0},
+	{"for i in 1 2; do for j in 1 2; do helper echo $i$j; break 2; done; done", "11\n", 0},
+	{"for i in 1 2; do for j in 1 2; do helper echo $i$j; break 3; done; done", "11\n", 0},
+	{"while helper exit 0; do helper echo foo; break; done", "foo\n", 0},
+	{"until helper exit 1; do helper echo foo; break; done", "foo\n", 0},
+	{"for i in 1 2 3; do if ((i == 2)); then break; fi; helper echo $i; done", "1\n", 0},
+	{"for i in 1 2 3; do case $i in 2) break;; esac; helper echo $i; done", "1\n", 0},
+	{"for i in 1 2 3; do { helper echo $i; break; }; done", "1\n", 0},
+	{"for i in 1 2 3; do (helper echo $i; break; helper echo x); done", "1\n2\n3\n", 0},
+	{"for i in 1 2 3; do helper echo $i; done; break; helper echo foo", "1\n2\n3\nfoo\n", 0},
+	// continue
+	{"for i in 1 2 3; do continue; helper echo $i; done", "", 0},
+	{"for i in 1 2 3; do if ((i == 2)); then continue; fi; helper echo $i; done", "1\n3\n", 0},
+	{"for i in 1 2; do for j in 1 2; do helper echo $i$j; continue 2; done; done", "11\n21\n", 0},
+	{"X=; while ((${#X} < 3)); do X=x$X; if ((${#X} == 2)); then continue; fi; helper echo $X; done", "x\nxxx\n", 0},
+	// function
+	{"f() { helper echo foo; }; f", "foo\n", 0},
+	{"f() { helper echo $# $1 $2; }; f foo bar", "2 foo bar\n", 0},
+	{"f() { helper echo $0; }; f foo", name + "\n", 0},
+	{"f() { helper echo $1; }; f foo; helper echo $#", "foo\n0\n", 0},
+	{"f() { g() { helper echo $1; }; g bar; helper echo $1; }; f foo", "bar\nfoo\n", 0},
+	{"f() { helper exit 3; }; f", "", 3},
+	{"f() { helper echo foo; }; f() { helper echo bar; }; f", "bar\n", 0},
+	{"f() { helper echo foo; } >&2; f", "", 0},
+	{"f() { helper echo foo; }; f | helper wc", "1\n", 0},
+	{"f() { FOO=bar; }; f; helper echo $FOO", "bar\n", 0},
+	{"f() { helper getenv FOO; }; FOO=foo f; helper echo ${FOO-unset}", "FOO=foo\nunset\n", 0},
+	{"FOO=foo; f() { helper echo $FOO; }; FOO=bar f; helper echo $FOO", "bar\nfoo\n", 0},
+	{"(f() { helper echo foo; }); f", "", 127},
+	{"f() { if ((${#1} < 3)); then f x$1; fi; helper echo $1; }; f ''", "xxx\nxx\nx\n\n", 0},
+	// return
+	{"f() { return; helper echo foo; }; f", "", 0},
+	{"f() { return 3; }; f", "", 3},
+	{"f() { helper exit 3; return; }; f", "", 3},
+	{"f() { return 256; }; f", "", 0},
+	{"f() { for i in 1 2; do return $i; done; }; f", "", 1},
+	{"f() { (return 3); helper echo $?; }; f", "3\n", 0},
+	{"f() { return 3; }; f; helper echo $?", "3\n", 0},
+	{"f() { g() { return 3; }; g; helper echo $?; }; f", "3\n", 0},
+	{"for i in 1 2; do f() { break; }; f; helper echo $i; done", "1\n2\n", 0},
+	// redirection
+	{"{ helper echo foo; helper echo bar; } >&2", "", 0},
+	{"for i in 1 2; do helper echo $i; done | helper wc", "2\n", 0},
+	// not found
+	{"go.sh-not-found", "", 127},
+	{"./go.sh-not-found", "", 127},
+}
+
+func TestRun(t *testing.T) {
+	for _, tt := range runTests {
+		env, stdout, _ := newTestEnv(t)
+		status, err := run(env, tt.src)
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+var errExitTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	{"set -e; helper exit 3; helper echo foo", "", 3},
+	{"set -e; helper exit 0; helper echo foo", "foo\n", 0},
+	{"set -e; set +e; helper exit 3; helper echo foo", "foo\n", 0},
+	{"set -e; trap 'helper echo EXIT $?' EXIT; helper exit 3; helper echo foo", "EXIT 3\n", 3},
+	{"set -e; trap 'helper echo ERR' ERR; helper exit 3; helper echo foo", "ERR\n", 3},
+	// pipeline
+	{"set -e; helper exit 1 | helper exit 0; helper echo foo", "foo\n", 0},
+	{"set -e; helper exit 0 | helper exit 3; helper echo foo", "", 3},
+	{"set -e; ! helper exit 0; helper echo foo", "foo\n", 0},
+	{"set -e; ! helper exit 3; helper echo foo", "foo\n", 0},
+	{"set -e; (helper exit 1; helper echo foo) | helper wc; helper echo bar", "0\nbar\n", 0},
+	// AND-OR list
+	{"set -e; helper exit 1 && helper echo foo; helper echo bar", "bar\n", 0},
+	{"set -e; helper exit 1 || helper echo foo; helper echo bar", "foo\nbar\n", 0},
+	{"set -e; helper exit 0 && helper exit 3; helper echo foo", "", 3},
+	{"set -e; helper exit 1 || helper exit 3; helper echo foo", "", 3},
+	// conditions
+	{"set -e; if helper exit 1; then :; fi; helper echo foo", "foo\n", 0},
+	{"set -e; if :; then helper exit 3; fi; helper echo foo", "", 3},
+	{"set -e; if helper exit 1; then :; elif helper exit 1; then :; else helper echo foo; fi", "foo\n", 0},
+	{"set -e; while helper exit 1; do :; done; helper echo foo", "foo\n", 0},
+	{"set -e; until helper exit 0; do :; done; helper echo foo", "foo\n", 0},
+	{"set -e; for i in 1; do helper exit 3; done; helper echo foo", "", 3},
+	{"set -e; if { helper exit 1; helper echo foo; }; then helper echo bar; fi", "foo\nbar\n", 0},
+	{"set -e; if (helper exit 1; helper echo foo); then helper echo bar; fi", "foo\nbar\n", 0},
+	{"set -e; f() { helper exit 1; helper echo foo; }; if f; then helper echo bar; fi", "foo\nbar\n", 0},
+	{"set -e; f() { helper exit 1; helper echo foo; }; f || :; ! f; helper echo bar", "foo\nfoo\nbar\n", 0},
+	// compound commands
+	{"set -e; { helper exit 3; helper echo foo; }; helper echo bar", "", 3},
+	{"set -e; { helper exit 1 && :; }; helper echo foo", "foo\n", 0},
+	{"set -e; { ! :; }; helper echo foo", "foo\n", 0},
+	{"set -e; for i in 1; do helper exit 1 && :; done; helper echo foo", "foo\n", 0},
+	{"set -e; case x in x) helper exit 1 && :;; esac; helper echo foo", "foo\n", 0},
+	{"set -e; if :; then helper exit 1 && :; fi; helper echo foo", "foo\n", 0},
+	{"set -e; (helper exit 3; helper echo foo); helper echo bar", "", 3},
+	{"set -e; (helper exit 1 && :); helper echo foo", "", 1},
+	// simple commands
+	{"set -e; f() { helper exit 1 && :; }; f; helper echo foo", "", 1},
+	{"set -e; f() { return 3; }; f; helper echo foo", "", 3},
+	{"set -e; eval 'helper exit 1 && :'; helper echo foo", "", 1},
+	{"set -e; X=$(helper exit 3); helper echo foo", "", 3},
+	{"set -e; helper echo $(helper exit 3) foo", "foo\n", 0},
+	{"set -e; helper echo $(helper exit 1; helper echo foo) bar", "bar\n", 0},
+	{"set -e; helper exit 3 &\nwait $!; helper echo foo", "", 3},
+}
+
+func TestErrExit(t *testing.T) {
+	for _, tt := range errExitTests {
+		env, stdout, _ := newTestEnv(t)
+		status, err := run(env, tt.src)
+		var eerr *interp.ExitError
+		if errors.As(err, &eerr) {
+			status = eerr.Status
+			err = nil
+		}
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	env, _, stderr := newTestEnv(t)
+	env.Set("PATH", "")
+	if status, err := run(env, "go.sh-not-found"); err != nil || status != 127 {
+		t.Fatalf("expected 127, got %v (%v)", status, err)
+	}
+	if g, e := stderr.String(), "go.sh-not-found: not found\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+func TestBrokenPipe(t *testing.T) {
+	env, stdout, stderr := newTestEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := runContext(ctx, env, "(trap 'echo $? >&2' EXIT; while :; do echo foo; done; echo bar) | helper exit 0; echo $?"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := stdout.String(), "0\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	// the error message of EPIPE is printed once
+	if g := stderr.String(); !strings.HasPrefix(g, "echo: write |1: ") || !strings.HasSuffix(g, "\n141\n") || strings.Count(g, "\n") != 2 {
+		t.Errorf("unexpected output: %q", g)
+	}
+}
+
+var runContextTests = []string{
+	"helper exit 0; helper sleep 10000",
+	"helper yes | helper wc",
+	"while :; do :; done",
+	"f() { while :; do :; done; }; f",
+	"for i in 1 2 3; do (while :; do :; done); done",
+	"X=$(while :; do :; done)",
+	"((X = 1)); while ((X)); do :; done",
+}
+
+func TestAsync(t *testing.T) {
+	for _, monitor := range []bool{false, true} {
+		env, stdout, _ := newTestEnv(t)
+		env.Stdin = strings.NewReader("foo\n")
+		if monitor {
+			env.Opts |= interp.Monitor
+		}
+		if _, err := run(env, "helper cat & wait"); err != nil {
+			t.Fatal(err)
+		}
+		var e string
+		if monitor {
+			e = "foo\n"
+		}
+		if g := stdout.String(); g != e {
+			t.Errorf("expected %q, got %q", e, g)
+		}
+	}
+
+	env, stdout, _ := newTestEnv(t)
+	if _, err := run(env, "helper exit 0 & X=$!; { :; } & helper echo $X $!"); err != nil {
+		t.Fatal(err)
+	}
+	ids := strings.Fields(stdout.String())
+	if len(ids) != 2 {
+		t.Fatalf("unexpected output: %q", stdout)
+	}
+	for _, s := range ids {
+		if id, err := strconv.Atoi(s); err != nil || id <= 0 {
+			t.Errorf("unexpected process ID: %q", s)
+		}
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("expected unique IDs, got %q", ids)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	for _, src := range runContextTests {
+		for _, timeout := range []bool{false, true} {
+			env, stdout, _ := newTestEnv(t)
+			if _, err := run(env, "trap 'helper echo foo' EXIT"); err != nil {
+				t.Fatal(err)
+			}
+			var ctx context.Context
+			var cancel context.CancelFunc
+			if timeout {
+				ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+			} else {
+				ctx, cancel = context.WithCancel(context.Background())
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+			_, err := runContext(ctx, env, src)
+			cancel()
+			var cerr *interp.ContextError
+			switch {
+			case !errors.As(err, &cerr):
+				t.Errorf("%q: expected *ContextError, got %#v", src, err)
+			case cerr.Timeout() != timeout:
+				t.Errorf("%q: expected Timeout() = %v, got %v", src, timeout, cerr.Timeout())
+			case timeout && !errors.Is(err, context.DeadlineExceeded):
+				t.Errorf("%q: expected %v, got %v", src, context.DeadlineExceeded, err)
+			case !timeout && !errors.Is(err, context.Canceled):
+				t.Errorf("%q: expected %v, got %v", src, context.Canceled, err)
+			}
+			if g, e := stdout.String(), "foo\n"; g != e {
+				t.Errorf("%q: expected %q, got %q", src, e, g)
+			}
+		}
+	}
+}
+
+func TestExecHandler(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	var cmds []*interp.Cmd
+	env.ExecHandler = func(ctx context.Context, cmd *interp.Cmd) (int, error) {
+		cmds = append(cmds, cmd)
+		switch cmd.Args[1] {
+		case "deny":
+			return 0, errors.New("denied")
+		case "echo":
+			fmt.Fprintln(cmd.Stdout, strings.Join(cmd.Args[2:], " "))
+		}
+		return len(cmds), nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch status, err := run(env, "export FOO=foo; helper echo foo; helper echo bar; BAR=bar helper exit 0"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 3:
+		t.Errorf("expected 3, got %v", status)
+	}
+	if g, e := len(cmds), 3; g != e {
+		t.Fatalf("expected %v, got %v", e, g)
+	}
+	for i, args := range [][]string{{exe, "echo", "foo"}, {exe, "echo", "bar"}, {exe, "exit", "0"}} {
+		cmd := cmds[i]
+		if !reflect.DeepEqual(cmd.Args, args) {
+			t.Errorf("expected %q, got %q", args, cmd.Args)
+		}
+		if cmd.Path != exe {
+			t.Errorf("expected %q, got %q", exe, cmd.Path)
+		}
+		if cmd.Dir != wd {
+			t.Errorf("expected %q, got %q", wd, cmd.Dir)
+		}
+		if !slices.Contains(cmd.Env, "FOO=foo") || !slices.Contains(cmd.Env, "GO_SH_HELPER=1") {
+			t.Errorf("unexpected environment: %q", cmd.Env)
+		}
+		if g, e := slices.Contains(cmd.Env, "BAR=bar"), i == 2; g != e {
+			t.Errorf("expected BAR=bar %v, got %v", e, g)
+		}
+	}
+	if g, e := stdout.String(), "foo\nbar\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+
+	switch _, err := run(env, "helper deny; helper echo foo"); {
+	case err == nil:
+		t.Error("expected error")
+	case err.Error() != "denied":
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+var xtraceTests = []struct {
+	src    string
+	stderr string
+}{
+	{"set -x; :", "+ :\n"},
+	{"set -x; : foo 'foo bar' \"it's\" '' a=b '~'", "+ : foo 'foo bar' 'it'\\''s' '' a=b '~'\n"},
+	{"set -x; X='foo bar' Y=; : $X", "+ X='foo bar' Y=''\n+ : foo bar\n"},
+	{"set -x; X=foo :", "+ X=foo :\n"},
+	{"set -x; f() { : $1; }; f foo", "+ f foo\n+ : foo\n"},
+	{"set -x; : 2>&1", "+ :\n"},
+	{"set -x; : $(:)", "+ :\n+ :\n"},
+	{"set -x; set +x; :", "+ set +x\n"},
+	{"PS4='$LINENO$(: ignored) '; set -x; :\n:", "1 :\n2 :\n"},
+	{"PS4=; set -x; :", ":\n"},
+	{"PS4='${'; set -x; :", "PS4:1:1: syntax error: reached EOF while looking for matching '}'\n${:\n"},
+}
+
+func TestXTrace(t *testing.T) {
+	for _, tt := range xtraceTests {
+		env, stdout, stderr := newTestEnv(t)
+		switch _, err := run(env, tt.src); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case stdout.String() != "":
+			t.Errorf("%q: unexpected output: %q", tt.src, stdout)
+		case stderr.String() != tt.stderr:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stderr, stderr)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	var evs []interp.TraceEvent
+	env.Trace = func(ev interp.TraceEvent) {
+		evs = append(evs, ev)
+	}
+	switch status, err := run(env, "X=foo\nf() { return 3; }; Y=$X f bar\n  helper sleep 10"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 0:
+		t.Errorf("expected 0, got %v", status)
+	}
+	if g, e := len(evs), 4; g != e {
+		t.Fatalf("expected %v, got %v", e, g)
+	}
+	for i, e := range []interp.TraceEvent{
+		{Pos: ast.NewPos(1, 1), Assigns: []string{"X=foo"}},
+		{Pos: ast.NewPos(2, 7), Args: []string{"return", "3"}, Status: 3},
+		{Pos: ast.NewPos(2, 20), Args: []string{"f", "bar"}, Assigns: []string{"Y=foo"}, Status: 3},
+	} {
+		g := evs[i]
+		g.Duration = 0
+		if !reflect.DeepEqual(g, e) {
+			t.Errorf("expected %+v, got %+v", e, g)
+		}
+	}
+	switch ev := evs[3]; {
+	case ev.Pos != ast.NewPos(3, 3):
+		t.Errorf("unexpected position: %v", ev.Pos)
+	case ev.Args[len(ev.Args)-2] != "sleep":
+		t.Errorf("unexpected arguments: %q", ev.Args)
+	case ev.Duration < 10*time.Millisecond:
+		t.Errorf("unexpected duration: %v", ev.Duration)
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	env.MaxCallDepth = 10
+	switch _, err := run(env, "f() { f; }; f"); {
+	case err == nil:
+		t.Error("expected error")
+	case !errors.Is(err, interp.ErrCallDepth):
+		t.Errorf("unexpected error: %v", err)
+	case err.Error() != "f: maximum function call depth exceeded (10)":
+		t.Errorf("unexpected error: %q", err)
+	}
+}
+
+func newTestEnv(t *testing.T) (env *interp.ExecEnv, stdout, stderr *strings.Builder) {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env = interp.NewExecEnv(name)
+	env.Aliases["helper"] = fmt.Sprintf("GO_SH_HELPER=1 GORACE=atexit_sleep_ms=0 '%v'", exe)
+	stdout = new(strings.Builder)
+	stderr = new(strings.Builder)
+	env.Stdin = strings.NewReader("")
+	env.Stdout = stdout
+	env.Stderr = stderr
+	return
+}
+
+func run(env *interp.ExecEnv, src string) (int, error) {
+	return runContext(context.Background(), env, src)
+}
+
+func runContext(ctx context.Context, env *interp.ExecEnv, src string) (status int, err error) {
+	r := strings.NewReader(src)
+	pr, err := parser.NewReader(r)
+	if err != nil {
+		return
+	}
+	for r.Len() > 0 {
+		var cmds []ast.Command
+		if cmds, _, err = parser.ParseCommands(env, name, pr); err != nil {
+			return
+		}
+		for _, cmd := range cmds {
+			if status, err = env.Run(ctx, cmd); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: expand.go
 //
-//   Copyright (c) 2021-2025 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -9,6 +9,7 @@
 package interp
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/user"
@@ -20,6 +21,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/parser"
 	"github.com/hattya/go.sh/pattern"
 )
 
@@ -54,7 +56,16 @@ const (
 
 // Expand expands a word into multiple fields.
 func (env *ExecEnv) Expand(word ast.Word, mode ExpMode) ([]string, error) {
-	fields, err := env.expand(word, mode)
+	return env.ExpandContext(context.Background(), word, mode)
+}
+
+// ExpandContext is like Expand but includes a context.
+//
+// The provided context is used to execute commands of command
+// substitutions, and to stop arithmetic expansion and pathname
+// expansion.
+func (env *ExecEnv) ExpandContext(ctx context.Context, word ast.Word, mode ExpMode) ([]string, error) {
+	fields, err := env.expand(ctx, word, mode)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +86,11 @@ func (env *ExecEnv) Expand(word ast.Word, mode ExpMode) ([]string, error) {
 						if env.Opts&NoGlob != 0 {
 							rv = append(rv, f.unquote())
 						} else {
-							rv = append(rv, env.expandPath(f)...)
+							paths, err := env.expandPath(ctx, f)
+							if err != nil {
+								return nil, err
+							}
+							rv = append(rv, paths...)
 						}
 					}
 				}
@@ -85,7 +100,66 @@ func (env *ExecEnv) Expand(word ast.Word, mode ExpMode) ([]string, error) {
 	return rv, nil
 }
 
-func (env *ExecEnv) expand(word ast.Word, mode ExpMode) (fields []*field, err error) {
+// Prompt returns the expansion of the value of the prompt variable named
+// by the name, which is one of PS1, PS2, and PS4. If it is unset, the
+// default value is returned. The commands executed by the expansion are
+// not traced. If History is not nil, each "!" in the expansion of PS1 is
+// replaced by the number of the next command in the history, and "!!"
+// is replaced by "!".
+func (env *ExecEnv) Prompt(ctx context.Context, name string) string {
+	v, set := env.Get(name)
+	if !set {
+		switch name {
+		case "PS1":
+			if os.Geteuid() == 0 {
+				return "# "
+			}
+			return "$ "
+		case "PS2":
+			return "> "
+		case "PS4":
+			return "+ "
+		}
+		return ""
+	}
+	word, err := parser.ParseWord(name, v.Value)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return v.Value
+	}
+
+	tracing, status, substStatus := env.tracing, env.status, env.substStatus
+	env.tracing = true
+	defer func() { env.tracing, env.status, env.substStatus = tracing, status, substStatus }()
+	fields, err := env.ExpandContext(ctx, word, Literal|Quote)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return v.Value
+	}
+	if name != "PS1" || env.History == nil {
+		return fields[0]
+	}
+	n := strconv.Itoa(env.History.Next())
+	var b strings.Builder
+	for s := fields[0]; s != ""; {
+		i := strings.IndexByte(s, '!')
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		if strings.HasPrefix(s[i:], "!!") {
+			b.WriteByte('!')
+			s = s[i+2:]
+		} else {
+			b.WriteString(n)
+			s = s[i+1:]
+		}
+	}
+	return b.String()
+}
+
+func (env *ExecEnv) expand(ctx context.Context, word ast.Word, mode ExpMode) (fields []*field, err error) {
 	fields = []*field{{}}
 	if mode&Quote != 0 {
 		fields[0].join("", true)
@@ -136,7 +210,7 @@ func (env *ExecEnv) expand(word ast.Word, mode ExpMode) (fields []*field, err er
 				}
 				fields[len(fields)-1].join(s, true)
 			case `"`:
-				word, err := env.expand(w.Value, mode&Arith|Quote)
+				word, err := env.expand(ctx, w.Value, mode&Arith|Quote)
 				if err != nil {
 					return nil, err
 				}
@@ -144,17 +218,26 @@ func (env *ExecEnv) expand(word ast.Word, mode ExpMode) (fields []*field, err er
 				fields = append(fields, word[1:]...)
 			}
 		case *ast.ParamExp:
-			if fields, err = env.expandParam(fields, w, mode); err != nil {
+			if fields, err = env.expandParam(ctx, fields, w, mode); err != nil {
 				return
 			}
+		case *ast.CmdSubst:
+			s, err := env.cmdSubst(ctx, w.List)
+			if err != nil {
+				return nil, err
+			}
+			fields[len(fields)-1].join(s, mode&Quote != 0)
 		case *ast.ArithExp:
-			word, err := env.expand(w.Expr, Arith)
+			word, err := env.expand(ctx, w.Expr, Arith)
 			if err != nil {
 				return nil, err
 			}
 			expr := env.join(word...).unquote()
-			n, err := env.Eval(expr)
+			n, err := env.EvalContext(ctx, expr)
 			if err != nil {
+				if _, ok := err.(ArithExprError); !ok {
+					return nil, err
+				}
 				err := err.(ArithExprError)
 				if expr != "" {
 					err.Expr = expr
@@ -235,7 +318,7 @@ func (env *ExecEnv) homeDir(name string) string {
 }
 
 // expandParam performs parameter expansion.
-func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode) ([]*field, error) {
+func (env *ExecEnv) expandParam(ctx context.Context, fields []*field, pe *ast.ParamExp, mode ExpMode) ([]*field, error) {
 	quote := mode&Quote != 0
 	var a []string
 	var set, null bool
@@ -282,6 +365,8 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 	case pe.Op == "":
 		// simplest form
 		switch {
+		case !set && env.Opts&NoUnset != 0:
+			goto Unset
 		case mode&Arith != 0 && !(env.isSpParam(pe.Name.Value) || env.isPosParam(pe.Name.Value)):
 			fields[len(fields)-1].join(pe.Name.Value, quote)
 		case set && !null:
@@ -311,7 +396,7 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 			case set && !null:
 				goto Param
 			case !set || pe.Op == ":-":
-				word, err := env.expand(pe.Word, mode&(Assign|Quote)|Literal)
+				word, err := env.expand(ctx, pe.Word, mode&(Assign|Quote)|Literal)
 				if err != nil {
 					return nil, err
 				}
@@ -330,11 +415,13 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 						Msg:      "cannot assign in this way",
 					}
 				}
-				word, err := env.expand(pe.Word, mode&Quote|Literal)
+				word, err := env.expand(ctx, pe.Word, mode&Quote|Literal)
 				if err != nil {
 					return nil, err
 				}
-				env.Set(pe.Name.Value, env.join(word...).unquote())
+				if err := env.assign(pe.Name.Value, env.join(word...).unquote()); err != nil {
+					return nil, err
+				}
 				fields[len(fields)-1].merge(word[0])
 				fields = append(fields, word[1:]...)
 			}
@@ -348,7 +435,7 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 				if len(pe.Word) == 0 {
 					msg = "parameter is unset or null"
 				} else {
-					word, err := env.expand(pe.Word, mode&Quote|Literal)
+					word, err := env.expand(ctx, pe.Word, mode&Quote|Literal)
 					if err != nil {
 						return nil, err
 					}
@@ -362,7 +449,7 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 		case ":+", "+":
 			// use alternative values
 			if set && (!null || pe.Op == "+") {
-				word, err := env.expand(pe.Word, mode&(Assign|Quote)|Literal)
+				word, err := env.expand(ctx, pe.Word, mode&(Assign|Quote)|Literal)
 				if err != nil {
 					return nil, err
 				}
@@ -374,7 +461,7 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 			switch {
 			case set && !null:
 				{
-					word, err := env.expand(pe.Word, Pattern)
+					word, err := env.expand(ctx, pe.Word, Pattern)
 					if err != nil {
 						return nil, err
 					}
@@ -404,7 +491,7 @@ func (env *ExecEnv) expandParam(fields []*field, pe *ast.ParamExp, mode ExpMode)
 			switch {
 			case set && !null:
 				{
-					word, err := env.expand(pe.Word, Pattern)
+					word, err := env.expand(ctx, pe.Word, Pattern)
 					if err != nil {
 						return nil, err
 					}
@@ -447,6 +534,32 @@ Unset:
 	}
 }
 
+// cmdSubst performs command substitution.
+func (env *ExecEnv) cmdSubst(ctx context.Context, cmds []ast.Command) (string, error) {
+	var s string
+	var status int
+	var err error
+	if env.CmdSubst != nil {
+		s, status, err = env.CmdSubst(ctx, env, cmds)
+	} else {
+		sub := env.Subshell()
+		var b strings.Builder
+		sub.Stdout = &b
+		for _, cmd := range cmds {
+			if status, err = sub.runCommand(ctx, cmd); err != nil {
+				break
+			}
+		}
+		status, err = sub.exit(ctx, status, err)
+		s = b.String()
+	}
+	if err != nil {
+		return "", err
+	}
+	env.substStatus = status
+	return strings.TrimRight(s, "\n"), nil
+}
+
 // split performs field splitting.
 func (env *ExecEnv) split(f *field) []*field {
 	var ifs string
@@ -525,12 +638,15 @@ func (env *ExecEnv) ifs() string {
 }
 
 // expandPath performs pathname expansion.
-func (env *ExecEnv) expandPath(f *field) []string {
-	paths, err := pattern.Glob(f.pattern())
-	if err != nil || len(paths) == 0 {
-		return []string{f.unquote()}
+func (env *ExecEnv) expandPath(ctx context.Context, f *field) ([]string, error) {
+	paths, err := env.glob(ctx, f.pattern())
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil || len(paths) == 0:
+		return []string{f.unquote()}, nil
 	}
-	return paths
+	return paths, nil
 }
 
 // ParamExpError represents an error in parameter expansion.
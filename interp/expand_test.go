@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: expand_test.go
 //
-//   Copyright (c) 2021-2025 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -9,6 +9,7 @@
 package interp_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/user"
@@ -111,6 +112,15 @@ var paramExpTests = []struct {
 }{
 	// simplest form
 	{word(paramExp(lit("V"), "", nil)), []string{V}, "", false},
+	{word(paramExp(lit("E"), "", nil)), []string{""}, "", false},
+	{word(paramExp(lit("_"), "", nil)), nil, "$_: parameter is unset", false},
+	{word(paramExp(lit("1"), "", nil)), nil, "$1: parameter is unset", false},
+	{word(paramExp(lit("@"), "", nil)), []string{""}, "", false},
+	{word(paramExp(lit("*"), "", nil)), []string{""}, "", false},
+	{word(paramExp(lit("#"), "", nil)), []string{"0"}, "", false},
+	{word(arithExp(word(paramExp(lit("_"), "", nil)))), nil, "$_: parameter is unset", false},
+	{word(arithExp(word(lit("_")))), nil, "_: parameter is unset", false},
+	{word(arithExp(word(lit("_ = 1")))), []string{"1"}, "", false},
 	// use default values
 	{word(paramExp(lit("V"), ":-", word(lit("...")))), []string{V}, "", false},
 	{word(paramExp(lit("V"), "-", word(lit("...")))), []string{V}, "", false},
@@ -562,6 +572,109 @@ func TestExpand(t *testing.T) {
 	})
 }
 
+var cmdSubstTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	{"helper echo $(helper echo foo)", "foo\n", 0},
+	{"helper echo `helper echo foo`", "foo\n", 0},
+	{"helper echo \"$(helper echo foo)\"", "foo\n", 0},
+	{"helper echo $(helper echo foo; helper echo bar)", "foo bar\n", 0},
+	{"helper echo \"$(helper echo foo; helper echo bar)\"", "foo\nbar\n", 0},
+	{"helper echo x$(helper echo)x", "xx\n", 0},
+	{"helper echo \"$(helper echo foo; helper echo; helper echo)\"", "foo\n", 0},
+	{"helper echo $(helper echo '  foo  bar  ')", "foo bar\n", 0},
+	{"helper echo \"$(helper echo '  foo  bar  ')\"", "  foo  bar  \n", 0},
+	{"IFS=:; helper echo $(helper echo foo:bar)", "foo bar\n", 0},
+	{"helper echo $(helper echo $(helper echo foo))", "foo\n", 0},
+	{"helper echo ${FOO:-$(helper echo foo)}", "foo\n", 0},
+	{"helper echo $(( $(helper echo 1) + 1 ))", "2\n", 0},
+	{"helper echo $(helper echo foo | helper cat)", "foo\n", 0},
+	{"helper echo $(helper echo2 foo 2>&1)", "foo\n", 0},
+	{"FOO=$(helper echo foo); helper echo $FOO", "foo\n", 0},
+	{"helper echo $(FOO=foo); helper echo ${FOO-unset}", "\nunset\n", 0},
+	// exit status
+	{"FOO=$(helper exit 3)", "", 3},
+	{"FOO=$(helper exit 3) BAR=$(helper exit 0)", "", 0},
+	{"helper exit 3; FOO=$(helper exit 0)", "", 0},
+	{"helper exit 3; FOO=foo", "", 0},
+	{"helper echo $(helper exit 3)", "\n", 0},
+}
+
+func TestCmdSubst(t *testing.T) {
+	for _, tt := range cmdSubstTests {
+		env, stdout, _ := newTestEnv(t)
+		status, err := run(env, tt.src)
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+	t.Run("Func", func(t *testing.T) {
+		env := interp.NewExecEnv(name)
+		env.CmdSubst = func(_ context.Context, _ *interp.ExecEnv, cmds []ast.Command) (string, int, error) {
+			var b strings.Builder
+			for _, cmd := range cmds {
+				printer.Fprint(&b, cmd)
+				b.WriteString("\n\n")
+			}
+			return b.String(), 0, nil
+		}
+		w := word(&ast.CmdSubst{
+			Dollar: true,
+			List: []ast.Command{
+				&ast.Cmd{
+					Expr: &ast.SimpleCmd{
+						Args: []ast.Word{word(lit("foo")), word(lit("bar"))},
+					},
+				},
+			},
+		})
+		g, err := env.Expand(w, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := []string{"foo", "bar"}; !reflect.DeepEqual(g, e) {
+			t.Errorf("expected %#v, got %#v", e, g)
+		}
+	})
+}
+
+var promptTests = []struct {
+	name, value string
+	prompt      string
+	stderr      string
+}{
+	{"PS2", "", "> ", ""},
+	{"PS4", "", "+ ", ""},
+	{"PS1", "$V> ", "value> ", ""},
+	{"PS1", "$(echo foo)$((1 + 2))> ", "foo3> ", ""},
+	{"PS1", "'$V'*> ", "'value'*> ", ""},
+	{"PS1", "${V> ", "${V> ", "PS1:1:1: syntax error: reached EOF while looking for matching '}'\n"},
+}
+
+func TestPrompt(t *testing.T) {
+	for _, tt := range promptTests {
+		env, _, stderr := newTestEnv(t)
+		env.Set("V", V)
+		env.Unset(tt.name)
+		if tt.value != "" {
+			env.Set(tt.name, tt.value)
+		}
+		if g, e := env.Prompt(context.Background(), tt.name), tt.prompt; g != e {
+			t.Errorf("%v=%q: expected %q, got %q", tt.name, tt.value, e, g)
+		}
+		if g, e := stderr.String(), tt.stderr; g != e {
+			t.Errorf("%v=%q: expected %q, got %q", tt.name, tt.value, e, g)
+		}
+	}
+}
+
 func word(w ...ast.WordPart) ast.Word {
 	if len(w) == 0 {
 		return ast.Word{}
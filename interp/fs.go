@@ -0,0 +1,210 @@
+//
+// go.sh/interp :: fs.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hattya/go.sh/pattern"
+)
+
+// OpenFileFS is the interface implemented by a file system which can open
+// a file for writing. The file returned by OpenFile should implement
+// io.Writer when flag contains os.O_WRONLY or os.O_RDWR.
+type OpenFileFS interface {
+	fs.FS
+
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+}
+
+// errNotDir indicates that the file is not a directory.
+var errNotDir = errors.New("not a directory")
+
+// fsName returns the name of the file named by the pathname in FS.
+func (env *ExecEnv) fsName(name string) string {
+	name = filepath.ToSlash(name)
+	if !path.IsAbs(name) {
+		name = path.Join(env.getwd(), name)
+	}
+	if name = path.Clean(name)[1:]; name == "" {
+		return "."
+	}
+	return name
+}
+
+// hostName returns the name of the file named by the pathname on the file
+// system of the host operating system. A relative pathname is resolved
+// against the working directory of the shell execution environment.
+func (env *ExecEnv) hostName(name string) string {
+	switch {
+	case name == "" || filepath.IsAbs(name):
+		return name
+	case filepath.VolumeName(name) == "" && os.IsPathSeparator(name[0]):
+		// rooted path on Windows
+		return filepath.VolumeName(env.getwd()) + name
+	}
+	return filepath.Join(env.getwd(), name)
+}
+
+// hostErr replaces the path of err with the pathname before resolved by
+// hostName.
+func hostErr(err error, name string) error {
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		perr.Path = name
+	}
+	return err
+}
+
+// stat returns a FileInfo describing the named file.
+func (env *ExecEnv) stat(name string) (fs.FileInfo, error) {
+	if env.FS == nil {
+		fi, err := os.Stat(env.hostName(name))
+		return fi, hostErr(err, name)
+	}
+	return fs.Stat(env.FS, env.fsName(name))
+}
+
+// lstat is like stat, but does not follow the symbolic link if FS
+// supports it.
+func (env *ExecEnv) lstat(name string) (fs.FileInfo, error) {
+	switch fsys := env.FS.(type) {
+	case nil:
+		fi, err := os.Lstat(env.hostName(name))
+		return fi, hostErr(err, name)
+	case interface {
+		Lstat(string) (fs.FileInfo, error)
+	}:
+		return fsys.Lstat(env.fsName(name))
+	}
+	return env.stat(name)
+}
+
+// openFile opens the named file with the specified flag.
+func (env *ExecEnv) openFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	switch fsys := env.FS.(type) {
+	case nil:
+		f, err := os.OpenFile(env.hostName(name), flag, perm)
+		if err != nil {
+			return nil, hostErr(err, name)
+		}
+		return f, nil
+	case OpenFileFS:
+		return fsys.OpenFile(env.fsName(name), flag, perm)
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0 {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrPermission,
+		}
+	}
+	return env.FS.Open(env.fsName(name))
+}
+
+// readFile reads the named file.
+func (env *ExecEnv) readFile(name string) ([]byte, error) {
+	if env.FS == nil {
+		b, err := os.ReadFile(env.hostName(name))
+		return b, hostErr(err, name)
+	}
+	return fs.ReadFile(env.FS, env.fsName(name))
+}
+
+// chdir checks whether the named directory can be the working directory.
+// It does not change the working directory of the process.
+func (env *ExecEnv) chdir(name string) error {
+	fi, err := env.stat(name)
+	switch {
+	case err != nil:
+		return err
+	case !fi.IsDir():
+		err = errNotDir
+	case env.FS == nil && !access(env.hostName(name), fi, 1):
+		err = fs.ErrPermission
+	default:
+		return nil
+	}
+	return &fs.PathError{
+		Op:   "chdir",
+		Path: name,
+		Err:  err,
+	}
+}
+
+// Getwd returns the working directory of the shell execution environment.
+func (env *ExecEnv) Getwd() string {
+	return env.getwd()
+}
+
+// Chdir changes the working directory of the shell execution environment
+// to dir, and updates the PWD and OLDPWD variables. It does not change
+// the working directory of the process.
+func (env *ExecEnv) Chdir(dir string) error {
+	wd := env.getwd()
+	if env.FS != nil {
+		if dir = filepath.ToSlash(dir); !path.IsAbs(dir) {
+			dir = path.Join(wd, dir)
+		}
+		dir = path.Clean(dir)
+	} else {
+		dir = filepath.Clean(env.hostName(dir))
+	}
+	if err := env.chdir(dir); err != nil {
+		return err
+	}
+	return env.setwd(wd, dir)
+}
+
+// sameFile reports whether the both files are the same file.
+func (env *ExecEnv) sameFile(name1 string, fi1 fs.FileInfo, name2 string, fi2 fs.FileInfo) bool {
+	if env.FS == nil {
+		return os.SameFile(fi1, fi2)
+	}
+	return env.fsName(name1) == env.fsName(name2)
+}
+
+// glob returns pathnames that match the pattern.
+func (env *ExecEnv) glob(ctx context.Context, pat string) ([]string, error) {
+	if env.FS == nil {
+		return pattern.GlobDirContext(ctx, env.getwd(), pat)
+	}
+
+	// the pathname of FS is relative to its root
+	var root, wd string
+	if i := len(pat) - len(strings.TrimLeft(pat, "/")); i > 0 {
+		root = pat[:i]
+		pat = pat[i:]
+	} else if dir := env.fsName("."); dir != "." {
+		var b strings.Builder
+		for _, r := range dir {
+			switch r {
+			case '\\', '*', '?', '[':
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		wd = dir + "/"
+		pat = b.String() + "/" + pat
+	}
+	paths, err := pattern.GlobFSContext(ctx, env.FS, pat)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range paths {
+		paths[i] = root + strings.TrimPrefix(p, wd)
+	}
+	return paths, nil
+}
@@ -0,0 +1,198 @@
+//
+// go.sh/interp :: fs_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var fsTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	// pathname expansion
+	{"echo *", "a.sh bin foo\n", 0},
+	{"echo /foo/*", "/foo/bar /foo/baz.txt\n", 0},
+	{"echo //foo/b*", "//foo/bar //foo/baz.txt\n", 0},
+	{"echo */*.txt", "foo/baz.txt\n", 0},
+	{"cd foo; echo *", "bar baz.txt\n", 0},
+	{"cd foo; echo ../*.sh", "../a.sh\n", 0},
+	{"cd foo; echo _*", "_*\n", 0},
+	// cd
+	{"cd foo; pwd", "/foo\n", 0},
+	{"cd /foo/bar; cd ..; pwd; echo $OLDPWD", "/foo\n/foo/bar\n", 0},
+	{"cd -P foo/bar; pwd -P", "/foo/bar\n", 0},
+	{"cd _", "", 1},
+	{"cd a.sh", "", 1},
+	// test
+	{"test -f a.sh", "", 0},
+	{"test -f foo", "", 1},
+	{"test -d foo", "", 0},
+	{"test -e _", "", 1},
+	{"cd foo; test -s baz.txt", "", 0},
+	{"test -x bin/tool", "", 0},
+	{"test -x a.sh", "", 1},
+	{"test foo/bar -ef /foo/../foo/bar", "", 0},
+	// redirection
+	{"echo foo >f; read X <f; echo $X", "foo\n", 0},
+	{"cd foo; echo foo >f; echo bar >>/foo/f; { read X; read Y; } <f; echo $X $Y", "foo bar\n", 0},
+	{"read X <foo/baz.txt; echo $X", "baz\n", 0},
+	{"set -C; echo foo >a.sh", "", 1},
+	{"read X <_", "", 1},
+	// dot
+	{". ./a.sh; echo $FOO", "foo\n", 0},
+	{"PATH=/; . a.sh; echo $FOO", "foo\n", 0},
+}
+
+func TestFS(t *testing.T) {
+	for _, tt := range fsTests {
+		env, stdout, _ := newTestEnv(t)
+		env.FS = newMemFS()
+		env.Set("PWD", "/")
+		switch status, err := run(env, tt.src); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
+
+func TestFSExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires PATHEXT")
+	}
+
+	env, stdout, _ := newTestEnv(t)
+	env.FS = newMemFS()
+	env.Set("PWD", "/")
+	env.ExecHandler = func(_ context.Context, cmd *interp.Cmd) (int, error) {
+		b, err := fs.ReadFile(env.FS, strings.TrimPrefix(cmd.Path, "/"))
+		if err != nil {
+			return 126, nil
+		}
+		stdout.Write(b)
+		stdout.WriteString(cmd.Dir + "\n")
+		return 0, nil
+	}
+	if _, err := run(env, "PATH=/bin; cd foo; tool; a.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := stdout.String(), "tool\n/foo\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+func TestReadOnlyFS(t *testing.T) {
+	env, _, stderr := newTestEnv(t)
+	env.FS = newMemFS().MapFS
+	env.Set("PWD", "/")
+	switch status, err := run(env, "echo foo >f"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 1:
+		t.Errorf("expected 1, got %v", status)
+	}
+	if g, e := stderr.String(), "f: permission denied\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+// memFS is a writable in-memory file system.
+type memFS struct {
+	fstest.MapFS
+}
+
+func newMemFS() *memFS {
+	return &memFS{fstest.MapFS{
+		"a.sh":        {Data: []byte("FOO=foo\n"), Mode: 0o644},
+		"bin/tool":    {Data: []byte("tool\n"), Mode: 0o755},
+		"foo/bar":     {Mode: fs.ModeDir | 0o755},
+		"foo/baz.txt": {Data: []byte("baz\n"), Mode: 0o644},
+	}}
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	f, ok := m.MapFS[name]
+	switch {
+	case flag&(os.O_WRONLY|os.O_RDWR) == 0:
+		return m.Open(name)
+	case ok && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		f = &fstest.MapFile{Mode: perm}
+		m.MapFS[name] = f
+	case f.Mode.IsDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	w := &memFile{name: name, f: f}
+	if flag&os.O_TRUNC == 0 {
+		w.b.Write(f.Data)
+	}
+	if flag&(os.O_RDWR|os.O_APPEND) == os.O_RDWR {
+		w.r = bytes.NewReader(f.Data)
+	}
+	return w, nil
+}
+
+// memFile is a file of memFS opened for writing.
+type memFile struct {
+	name string
+	f    *fstest.MapFile
+	b    bytes.Buffer
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return memFileInfo{f}, nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.r == nil {
+		return 0, fs.ErrPermission
+	}
+	return f.r.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	n, err := f.b.Write(p)
+	f.f.Data = bytes.Clone(f.b.Bytes())
+	f.f.ModTime = time.Now()
+	return n, err
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+type memFileInfo struct {
+	f *memFile
+}
+
+func (fi memFileInfo) Name() string       { return fi.f.name[strings.LastIndexByte(fi.f.name, '/')+1:] }
+func (fi memFileInfo) Size() int64        { return int64(len(fi.f.f.Data)) }
+func (fi memFileInfo) Mode() fs.FileMode  { return fi.f.f.Mode }
+func (fi memFileInfo) ModTime() time.Time { return fi.f.f.ModTime }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,454 @@
+//
+// go.sh/interp :: history.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/parser"
+	"github.com/hattya/go.sh/printer"
+)
+
+// defaultHistSize is the number of the commands kept in the history when
+// the HISTSIZE variable is unset or invalid.
+const defaultHistSize = 128
+
+// History represents the command history of an interactive shell. The
+// commands are numbered in the order they were added to the history.
+type History struct {
+	mu   sync.Mutex
+	list []string
+	base int // number of list[0]
+}
+
+// NewHistory returns a new History.
+func NewHistory() *History {
+	return &History{base: 1}
+}
+
+// Len returns the number of the commands in the history.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.list)
+}
+
+// At returns the i-th command from the oldest one.
+func (h *History) At(i int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.list[i]
+}
+
+// Next returns the number of the next command added to the history.
+func (h *History) Next() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.base + len(h.list)
+}
+
+// push appends the commands to the history, and discards the old ones
+// so that the history has at most size commands. It reports whether any
+// commands were discarded.
+func (h *History) push(size int, cmds ...string) bool {
+	h.list = append(h.list, cmds...)
+	n := len(h.list) - size
+	if n <= 0 {
+		return false
+	}
+	h.list = slices.Delete(h.list, 0, n)
+	h.base += n
+	return true
+}
+
+// find returns the index of the command specified by s, which is a
+// number or a prefix of the command. A negative number is relative to
+// the latest command, which is the fc command being executed, and the
+// index is clamped to the commands before it.
+func (h *History) find(s string) (int, bool) {
+	last := len(h.list) - 2
+	if n, err := strconv.Atoi(s); err == nil {
+		i := n - h.base
+		if n < 0 {
+			i = last + 1 + n
+		}
+		return max(min(i, last), 0), true
+	}
+	for i := last; i >= 0; i-- {
+		if strings.HasPrefix(h.list[i], s) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// AddHistory adds the command to the history, and appends it to the file
+// named by the HISTFILE variable. The number of the commands kept in the
+// history is determined by the HISTSIZE variable. If the nolog option is
+// enabled, function definitions are not added to the history.
+func (env *ExecEnv) AddHistory(cmd ast.Command) {
+	if env.History == nil || (env.Opts&NoLog != 0 && isFuncDef(cmd)) {
+		return
+	}
+	env.pushHistory(false, histEntry(cmd))
+}
+
+// pushHistory appends the commands to the history. If replace is true,
+// they replace the latest command. The file named by the HISTFILE
+// variable is rewritten when the commands in the history were removed.
+func (env *ExecEnv) pushHistory(replace bool, cmds ...string) {
+	size := env.histSize()
+	h := env.History
+	h.mu.Lock()
+	if replace && len(h.list) > 0 {
+		h.list = h.list[:len(h.list)-1]
+	}
+	if h.push(size, cmds...) || replace {
+		cmds = slices.Clone(h.list)
+		h.mu.Unlock()
+		env.writeHistory(os.O_TRUNC, cmds)
+		return
+	}
+	h.mu.Unlock()
+	env.writeHistory(os.O_APPEND, cmds)
+}
+
+// LoadHistory reads the commands from the file named by the HISTFILE
+// variable into the history. It does nothing if the file does not
+// exist.
+func (env *ExecEnv) LoadHistory() error {
+	name := env.histFile()
+	if env.History == nil || name == "" {
+		return nil
+	}
+	b, err := env.readFile(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	var list []string
+	r := strings.NewReader(string(b))
+	pr, _ := parser.NewReader(r)
+	for r.Len() > 0 {
+		cmds, _, err := parser.ParseCommands(nil, name, pr)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			list = append(list, histEntry(cmd))
+		}
+	}
+	size := env.histSize()
+	h := env.History
+	h.mu.Lock()
+	trimmed := h.push(size, list...)
+	list = slices.Clone(h.list)
+	h.mu.Unlock()
+	if trimmed {
+		return env.writeHistory(os.O_TRUNC, list)
+	}
+	return nil
+}
+
+// writeHistory writes the commands to the file named by the HISTFILE
+// variable.
+func (env *ExecEnv) writeHistory(flag int, cmds []string) error {
+	name := env.histFile()
+	if name == "" {
+		return nil
+	}
+	f, err := env.openFile(name, os.O_WRONLY|os.O_CREATE|flag, 0o600)
+	if err != nil {
+		return err
+	}
+	w, ok := f.(io.Writer)
+	if !ok {
+		f.Close()
+		return &fs.PathError{
+			Op:   "write",
+			Path: name,
+			Err:  fs.ErrPermission,
+		}
+	}
+	var b strings.Builder
+	for _, s := range cmds {
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+	_, err = io.WriteString(w, b.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// histFile returns the value of the HISTFILE variable.
+func (env *ExecEnv) histFile() string {
+	v, _ := env.Get("HISTFILE")
+	return v.Value
+}
+
+// histSize returns the value of the HISTSIZE variable.
+func (env *ExecEnv) histSize() int {
+	if v, set := env.Get("HISTSIZE"); set {
+		if n, err := strconv.Atoi(v.Value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultHistSize
+}
+
+// histEntry returns the command in the form stored in the history.
+func histEntry(cmd ast.Command) string {
+	var b strings.Builder
+	printer.Fprint(&b, cmd)
+	return b.String()
+}
+
+// isFuncDef reports whether the command consists only of function
+// definitions.
+func isFuncDef(cmd ast.Command) bool {
+	switch cmd := cmd.(type) {
+	case ast.List:
+		for _, ao := range cmd {
+			if !isFuncDef(ao) {
+				return false
+			}
+		}
+		return len(cmd) > 0
+	case *ast.AndOrList:
+		return cmd.Sep != "&" && len(cmd.List) == 0 && isFuncDef(cmd.Pipeline)
+	case *ast.Pipeline:
+		return cmd.Bang.IsZero() && len(cmd.List) == 0 && isFuncDef(cmd.Cmd)
+	case *ast.Cmd:
+		_, ok := cmd.Expr.(*ast.FuncDef)
+		return ok
+	}
+	return false
+}
+
+// fcBuiltin lists the commands in the history, or edits and re-executes
+// them.
+func fcBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	var editor string
+	var list, noNum, rev, subst bool
+	args = args[1:]
+Options:
+	for ; len(args) > 0; args = args[1:] {
+		switch s := args[0]; {
+		case s == "--":
+			args = args[1:]
+			break Options
+		case len(s) < 2 || s[0] != '-':
+			break Options
+		case strings.Trim(s[1:], "0123456789") == "":
+			// negative number
+			break Options
+		default:
+			for i := 1; i < len(s); i++ {
+				switch s[i] {
+				case 'e':
+					switch {
+					case i+1 < len(s):
+						editor = s[i+1:]
+					case len(args) > 1:
+						args = args[1:]
+						editor = args[0]
+					default:
+						fmt.Fprintln(env.Stderr, "fc: -e: option requires an argument")
+						return 2, nil
+					}
+					i = len(s)
+				case 'l':
+					list = true
+				case 'n':
+					noNum = true
+				case 'r':
+					rev = true
+				case 's':
+					subst = true
+				default:
+					fmt.Fprintf(env.Stderr, "fc: -%c: invalid option\n", s[i])
+					return 2, nil
+				}
+			}
+		}
+	}
+	if editor == "-" {
+		// historical form of -s
+		subst = true
+	}
+	var old, repl string
+	if subst && !list && len(args) > 0 {
+		if i := strings.IndexByte(args[0], '='); i >= 0 {
+			old, repl = args[0][:i], args[0][i+1:]
+			args = args[1:]
+		}
+	}
+	switch {
+	case len(args) > 2,
+		len(args) > 1 && subst && !list:
+		fmt.Fprintln(env.Stderr, "fc: too many arguments")
+		return 2, nil
+	case env.History == nil:
+		fmt.Fprintln(env.Stderr, "fc: history is not available")
+		return 1, nil
+	}
+
+	// resolve the range of the commands
+	h := env.History
+	h.mu.Lock()
+	if len(h.list) < 2 {
+		h.mu.Unlock()
+		fmt.Fprintln(env.Stderr, "fc: history is empty")
+		return 1, nil
+	}
+	ops := []string{"-1", ""}
+	if list {
+		ops[0] = "-16"
+		ops[1] = "-1"
+	}
+	copy(ops, args)
+	if ops[1] == "" || (subst && !list) {
+		ops[1] = ops[0]
+	}
+	var idx [2]int
+	for i, s := range ops {
+		j, ok := h.find(s)
+		if !ok {
+			h.mu.Unlock()
+			fmt.Fprintf(env.Stderr, "fc: %v: no command found\n", s)
+			return 1, nil
+		}
+		idx[i] = j
+	}
+	first, last := idx[0], idx[1]
+	if first > last {
+		first, last = last, first
+		rev = !rev
+	}
+	nums := make([]int, 0, last-first+1)
+	cmds := make([]string, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		nums = append(nums, h.base+i)
+		cmds = append(cmds, h.list[i])
+	}
+	h.mu.Unlock()
+	if rev {
+		slices.Reverse(nums)
+		slices.Reverse(cmds)
+	}
+
+	if list {
+		var b strings.Builder
+		for i, s := range cmds {
+			for j, l := range strings.Split(s, "\n") {
+				if j == 0 && !noNum {
+					b.WriteString(strconv.Itoa(nums[i]))
+				}
+				b.WriteByte('\t')
+				b.WriteString(l)
+				b.WriteByte('\n')
+			}
+		}
+		io.WriteString(env.Stdout, b.String())
+		return 0, nil
+	}
+
+	text := strings.Join(cmds, "\n")
+	if subst {
+		text = strings.Replace(text, old, repl, 1)
+	} else {
+		if editor == "" {
+			editor = "ed"
+			if v, set := env.Get("FCEDIT"); set && v.Value != "" {
+				editor = v.Value
+			}
+		}
+		var status int
+		var err error
+		if text, status, err = env.fcEdit(ctx, editor, text); err != nil || status != 0 {
+			if err == nil {
+				// the fc command is not entered into the history
+				env.pushHistory(true)
+			}
+			return status, err
+		}
+	}
+
+	// parse the commands before they are executed
+	var parsed []ast.Command
+	r := strings.NewReader(text)
+	pr, _ := parser.NewReader(r)
+	for r.Len() > 0 {
+		cmds, _, err := parser.ParseCommands(env, "fc", pr)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return 2, nil
+		}
+		parsed = append(parsed, cmds...)
+	}
+	var entries []string
+	var b strings.Builder
+	for _, cmd := range parsed {
+		s := histEntry(cmd)
+		b.WriteString(s)
+		b.WriteByte('\n')
+		if env.Opts&NoLog == 0 || !isFuncDef(cmd) {
+			entries = append(entries, s)
+		}
+	}
+	io.WriteString(env.Stderr, b.String())
+	// the fc command is replaced by the commands
+	env.pushHistory(true, entries...)
+	return env.runList(ctx, parsed)
+}
+
+// fcEdit invokes the editor to edit the commands, and returns the edited
+// commands and the exit status of the editor.
+func (env *ExecEnv) fcEdit(ctx context.Context, editor, text string) (string, int, error) {
+	f, err := os.CreateTemp("", "fc-*.sh")
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "fc: %v\n", err)
+		return "", 1, nil
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "fc: %v\n", err)
+		return "", 1, nil
+	}
+
+	status, err := env.source(ctx, "fc", strings.NewReader(editor+" "+quote(f.Name())))
+	if err != nil || status != 0 {
+		return "", status, err
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "fc: %v\n", err)
+		return "", 1, nil
+	}
+	return string(b), 0, nil
+}
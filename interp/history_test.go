@@ -0,0 +1,201 @@
+//
+// go.sh/interp :: history_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+	"github.com/hattya/go.sh/parser"
+)
+
+var historyTests = []struct {
+	src     string
+	stdout  string
+	stderr  string
+	status  int
+	history []string
+}{
+	{"echo foo\necho bar\nfc -l", "foo\nbar\n1\techo foo\n2\techo bar\n", "", 0, []string{"echo foo", "echo bar", "fc -l"}},
+	{"echo foo\necho bar\nfc -l -1", "foo\nbar\n2\techo bar\n", "", 0, nil},
+	{"echo foo\necho bar\nfc -ln", "foo\nbar\n\techo foo\n\techo bar\n", "", 0, nil},
+	{"echo foo\necho bar\nfc -lr", "foo\nbar\n2\techo bar\n1\techo foo\n", "", 0, nil},
+	{"echo foo\necho bar\nfc -l 2 1", "foo\nbar\n2\techo bar\n1\techo foo\n", "", 0, nil},
+	{"echo foo\necho bar\nfc -l -r -- 0 100", "foo\nbar\n2\techo bar\n1\techo foo\n", "", 0, nil},
+	{"echo foo\necho bar\nfc -l 'echo f'", "foo\nbar\n1\techo foo\n2\techo bar\n", "", 0, nil},
+	{"for i in 1 2\ndo\n\techo $i\ndone\nfc -l", "1\n2\n1\tfor i in 1 2; do\n\t\techo $i\n\tdone\n", "", 0, nil},
+	{"echo foo; echo bar &\nwait\nfc -l 1 1", "foo\nbar\n1\techo foo; echo bar &\n", "", 0, nil},
+	{"HISTSIZE=2\necho foo\nfc -l", "foo\n2\techo foo\n", "", 0, []string{"echo foo", "fc -l"}},
+	{"set -o nolog\nf() { :; }\ng() { :; }; h() { :; }\nf() { :; } &\nfc -l", "1\tset -o nolog\n2\tf() { :; } &\n", "", 0, nil},
+	{"f() { :; }\nfc -l", "1\tf() { :; }\n", "", 0, nil},
+	{"echo foo\nfc -s", "foo\nfoo\n", "echo foo\n", 0, []string{"echo foo", "echo foo"}},
+	{"echo foo\necho bar\nfc -s foo=baz 1", "foo\nbar\nbaz\n", "echo baz\n", 0, []string{"echo foo", "echo bar", "echo baz"}},
+	{"echo foo\necho bar\nfc -e - bar=baz", "foo\nbar\nbaz\n", "echo baz\n", 0, []string{"echo foo", "echo bar", "echo baz"}},
+	{"echo foo\nfc -e true", "foo\nfoo\n", "echo foo\n", 0, []string{"echo foo", "echo foo"}},
+	{"echo foo\necho bar\nfc -etrue 1 2", "foo\nbar\nfoo\nbar\n", "echo foo\necho bar\n", 0, []string{"echo foo", "echo bar", "echo foo", "echo bar"}},
+	{"echo foo\nfc -e false", "foo\n", "", 1, []string{"echo foo"}},
+	{"edit() { echo 'echo bar' >\"$1\"; }\nFCEDIT=edit\necho foo\nfc", "foo\nbar\n", "echo bar\n", 0, nil},
+	{"echo foo\nfc -s foo='$(' 1", "foo\n", "fc:1:6: syntax error: unexpected EOF\n", 2, nil},
+	{"exit 3\n", "", "", 3, nil},
+	{"echo exit 3\nfc -s 'echo '=", "exit 3\n", "exit 3\n", 3, nil},
+	// errors
+	{"fc -l", "", "fc: history is empty\n", 1, nil},
+	{"echo foo\nfc -l bar", "foo\n", "fc: bar: no command found\n", 1, nil},
+	{"echo foo\nfc -s 1 2", "foo\n", "fc: too many arguments\n", 2, nil},
+	{"echo foo\nfc -l 1 2 3", "foo\n", "fc: too many arguments\n", 2, nil},
+	{"fc -x", "", "fc: -x: invalid option\n", 2, nil},
+	{"fc -e", "", "fc: -e: option requires an argument\n", 2, nil},
+}
+
+func TestHistory(t *testing.T) {
+	for _, tt := range historyTests {
+		env, stdout, stderr := newTestEnv(t)
+		env.History = interp.NewHistory()
+		switch status, err := runHistory(env, tt.src); {
+		case err != nil && !errors.As(err, new(*interp.ExitError)):
+			t.Fatal(err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		}
+		if g, e := stdout.String(), tt.stdout; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.src, e, g)
+		}
+		if g, e := stderr.String(), tt.stderr; g != e {
+			t.Errorf("%q: expected %q, got %q", tt.src, e, g)
+		}
+		if tt.history != nil {
+			if g, e := history(env.History), tt.history; !slices.Equal(g, e) {
+				t.Errorf("%q: expected %q, got %q", tt.src, e, g)
+			}
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(name, []byte("echo foo\nfor i in 1 2; do echo $i; done\necho bar; echo baz\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	env, _, _ := newTestEnv(t)
+	env.History = interp.NewHistory()
+	env.Set("HISTFILE", name)
+	env.Set("HISTSIZE", "2")
+	if err := env.LoadHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := history(env.History), []string{"for i in 1 2; do echo $i; done", "echo bar; echo baz"}; !slices.Equal(g, e) {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	if g, e := env.History.Next(), 4; g != e {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+	// trimmed
+	if g, e := readFile(t, name), "for i in 1 2; do echo $i; done\necho bar; echo baz\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+
+	env.Set("HISTSIZE", "3")
+	if _, err := runHistory(env, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := readFile(t, name), "for i in 1 2; do echo $i; done\necho bar; echo baz\ntrue\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	if _, err := runHistory(env, "false"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := readFile(t, name), "echo bar; echo baz\ntrue\nfalse\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+
+	// no such file
+	env = interp.NewExecEnv(name)
+	env.History = interp.NewHistory()
+	env.Set("HISTFILE", filepath.Join(t.TempDir(), "history"))
+	if err := env.LoadHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := env.History.Len(), 0; g != e {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+	// syntax error
+	if err := os.WriteFile(name, []byte("echo $(\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	env.Set("HISTFILE", name)
+	if err := env.LoadHistory(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestPromptHistory(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	env.History = interp.NewHistory()
+	env.Set("PS1", "!:!!:$V> ")
+	env.Set("V", "!")
+	if g, e := env.Prompt(context.Background(), "PS1"), "1:!:1> "; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	if _, err := runHistory(env, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := env.Prompt(context.Background(), "PS1"), "2:!:2> "; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	env.Set("PS2", "!> ")
+	if g, e := env.Prompt(context.Background(), "PS2"), "!> "; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+// runHistory adds each command of src to the history before it is
+// executed as an interactive shell does.
+func runHistory(env *interp.ExecEnv, src string) (status int, err error) {
+	r := strings.NewReader(src)
+	pr, err := parser.NewReader(r)
+	if err != nil {
+		return
+	}
+	for r.Len() > 0 {
+		cmds, _, err := parser.ParseCommands(env, name, pr)
+		if err != nil {
+			return 2, err
+		}
+		for _, cmd := range cmds {
+			env.AddHistory(cmd)
+			if status, err = env.Run(context.Background(), cmd); err != nil {
+				return status, err
+			}
+		}
+	}
+	return
+}
+
+func history(h *interp.History) []string {
+	list := make([]string, h.Len())
+	for i := range list {
+		list[i] = h.At(i)
+	}
+	return list
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: interp.go
 //
-//   Copyright (c) 2021-2025 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -11,20 +11,104 @@
 package interp
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/hattya/go.sh/ast"
 )
 
 const IFS = " \t\n"
 
+// DefaultMaxCallDepth is the default value of ExecEnv.MaxCallDepth.
+const DefaultMaxCallDepth = 1000
+
 // ExecEnv represents a shell execution environment.
+//
+// An ExecEnv has its own working directory, which is initialized to the
+// working directory of the process by NewExecEnv, and neither cd nor
+// Chdir changes the working directory of the process. Relative pathnames
+// are resolved against it, so that multiple ExecEnvs can be used from
+// multiple goroutines.
 type ExecEnv struct {
 	Args    []string
 	Opts    Option
 	Aliases map[string]string
 
-	vars map[string]Var
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// CmdSubst performs command substitution, and returns the standard
+	// output and the exit status of the commands. If it is nil, the
+	// commands are executed in the subshell environment returned by
+	// Subshell.
+	CmdSubst func(ctx context.Context, env *ExecEnv, cmds []ast.Command) (string, int, error)
+
+	// FS is the file system used for pathname expansion, redirections,
+	// and built-in utilities. If it is nil, the file system of the host
+	// operating system is used. Otherwise, a pathname is interpreted as
+	// a slash-separated path from the root of FS, and the working
+	// directory is taken from the PWD variable. If FS implements
+	// OpenFileFS, it is also used for output redirections.
+	FS fs.FS
+
+	// ExecHandler executes an external utility, and returns its exit
+	// status. If it is nil, DefaultExecHandler is used.
+	ExecHandler ExecHandler
+
+	// Builtins is a table of the regular built-in utilities. They are
+	// searched after functions and before the PATH variable.
+	Builtins map[string]Builtin
+
+	// MaxCallDepth is the maximum depth of nested function calls. If it
+	// is 0, the depth is not limited.
+	MaxCallDepth int
+
+	// Trace is called with a TraceEvent after each simple command was
+	// executed regardless of the xtrace option. It may be called
+	// concurrently from the subshell environments of a pipeline.
+	Trace func(ev TraceEvent)
+
+	// History is the command history of an interactive shell. If it is
+	// nil, the commands are not recorded, and the fc built-in utility is
+	// not available.
+	History *History
+
+	vars        cowMap[string, Var]
+	funcs       cowMap[string, *ast.FuncDef]
+	traps       map[string]string
+	ptraps      map[string]string
+	sig         *sigRelay
+	fds         map[int]any
+	files       []io.Closer
+	status      int
+	substStatus int
+	loop        int
+	depth       int
+	optInd      int
+	optPos      int
+	umask       int
+	wd          string
+	pid         int
+	lineno      int
+	jobs        *jobTable
+	bgPid       int
+	started     func(pid int)
+	job         *job
+	upstream    bool
+	cond        int
+	errIgnored  bool
+	errTrapped  bool
+	tracing     bool
 }
 
 // NewExecEnv returns a new ExecEnv.
@@ -32,26 +116,94 @@ func NewExecEnv(name string, args ...string) *ExecEnv {
 	env := &ExecEnv{
 		Args:    append([]string{name}, args...),
 		Aliases: make(map[string]string),
-		vars:    make(map[string]Var),
+		Stdin:   os.Stdin,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+
+		Builtins:     DefaultBuiltins(),
+		MaxCallDepth: DefaultMaxCallDepth,
+
+		umask: getUmask(),
+		jobs:  new(jobTable),
 	}
 	for _, s := range os.Environ() {
 		if i := strings.IndexByte(s[1:], '='); i != -1 {
-			env.vars[env.keyFor(s[:i+1])] = Var{
+			env.vars.set(env.keyFor(s[:i+1]), Var{
 				Name:   s[:i+1],
 				Value:  s[i+2:],
 				Export: true,
-			}
+			})
 		}
 	}
 	// shell variables
-	env.vars[env.keyFor("IFS")] = Var{
+	env.vars.set(env.keyFor("IFS"), Var{
 		Name:   "IFS",
 		Value:  IFS,
 		Export: true,
-	}
+	})
+	env.vars.set(env.keyFor("OPTIND"), Var{
+		Name:   "OPTIND",
+		Value:  "1",
+		Export: true,
+	})
+	// working directory
+	env.wd = env.initWd()
+	env.vars.set(env.keyFor("PWD"), Var{
+		Name:   "PWD",
+		Value:  env.wd,
+		Export: true,
+	})
+	// process IDs
+	env.pid = os.Getpid()
+	env.assign("PPID", strconv.Itoa(os.Getppid()))
 	return env
 }
 
+// Subshell returns a subshell environment, which is a copy of the shell
+// execution environment. Any changes made in it, such as the variables,
+// functions, options, and open files, do not affect the shell execution
+// environment. The traps which are not being ignored are reset.
+//
+// The variables and functions are shared until either of them modifies
+// them.
+func (env *ExecEnv) Subshell() *ExecEnv {
+	sub := *env
+	sub.Args = slices.Clone(env.Args)
+	sub.Aliases = maps.Clone(env.Aliases)
+	sub.Builtins = maps.Clone(env.Builtins)
+	sub.vars = env.vars.share()
+	sub.funcs = env.funcs.share()
+	// traps which are not being ignored are reset, but the ones of the
+	// parent are printed by trap until it is modified
+	sub.traps = nil
+	for k, action := range env.traps {
+		if action == "" {
+			if sub.traps == nil {
+				sub.traps = make(map[string]string)
+			}
+			sub.traps[k] = action
+		}
+	}
+	if len(env.traps) > 0 {
+		sub.ptraps = maps.Clone(env.traps)
+	}
+	sub.sig = nil
+	sub.fds = maps.Clone(env.fds)
+	sub.files = nil
+	sub.jobs = &jobTable{parent: env.jobs}
+	sub.started = nil
+	return &sub
+}
+
+// Alias returns the value of the alias named by the name.
+func (env *ExecEnv) Alias(name string) (string, bool) {
+	if env == nil {
+		return "", false
+	}
+	v, ok := env.Aliases[name]
+	return v, ok
+}
+
 // Get retrieves the variable named by the name.
 func (env *ExecEnv) Get(name string) (v Var, set bool) {
 	if len(name) == 1 {
@@ -60,12 +212,16 @@ func (env *ExecEnv) Get(name string) (v Var, set bool) {
 		case "#":
 			value = strconv.Itoa(len(env.Args) - 1)
 		case "?":
-			value = "0"
+			value = strconv.Itoa(env.status)
 		case "-":
 			value = env.Opts.String()
 		case "$":
-			value = strconv.Itoa(os.Getpid())
+			// the process ID of the shell even in a subshell
+			value = strconv.Itoa(env.pid)
 		case "!":
+			if env.bgPid != 0 {
+				value = strconv.Itoa(env.bgPid)
+			}
 		case "0":
 			value = env.Args[0]
 		default:
@@ -87,32 +243,101 @@ Default:
 			}
 			set = true
 		}
+	} else if k := env.keyFor(name); k == env.keyFor("LINENO") && env.lineno > 0 {
+		v = Var{
+			Name:  name,
+			Value: strconv.Itoa(env.lineno),
+		}
+		set = true
 	} else {
-		v, set = env.vars[env.keyFor(name)]
+		v, set = env.vars.m[k]
+		set = set && !v.unset
 	}
 	return
 }
 
-// Set sets the value of the variable named by the name.
-func (env *ExecEnv) Set(name, value string) {
+// Set sets the value of the variable named by the name, and preserves
+// its attributes. If the AllExport option is set, the variable is
+// exported. It returns an error wrapping ErrReadOnly if the variable is
+// readonly. The special and positional parameters cannot be set by Set.
+func (env *ExecEnv) Set(name, value string) error {
+	return env.assign(name, value)
+}
+
+// assign sets the value of the variable named by the name, and preserves
+// its attributes.
+func (env *ExecEnv) assign(name, value string) error {
 	if env.isSpParam(name) || env.isPosParam(name) {
-		return
+		return nil
 	}
-	env.vars[env.keyFor(name)] = Var{
-		Name:  name,
-		Value: value,
+	k := env.keyFor(name)
+	v := env.vars.m[k]
+	if v.ReadOnly {
+		return fmt.Errorf("%v: %w", name, ErrReadOnly)
 	}
+	v.Name = name
+	v.Value = value
+	v.unset = false
+	if env.Opts&AllExport != 0 {
+		v.Export = true
+	}
+	env.vars.set(k, v)
+	return nil
+}
+
+// Unset unsets the variable named by the name. It returns an error
+// wrapping ErrReadOnly if the variable is readonly.
+func (env *ExecEnv) Unset(name string) error {
+	return env.unset(name)
 }
 
-// Unset unsets the variable named by the name.
-func (env *ExecEnv) Unset(name string) {
-	delete(env.vars, env.keyFor(name))
+// unset unsets the variable named by the name unless it is readonly.
+func (env *ExecEnv) unset(name string) error {
+	k := env.keyFor(name)
+	if v, ok := env.vars.m[k]; ok && v.ReadOnly {
+		return fmt.Errorf("%v: %w", name, ErrReadOnly)
+	}
+	env.vars.delete(k)
+	return nil
+}
+
+// attr sets the attributes of the variable named by the name. If value
+// is not nil, it is assigned to the variable.
+func (env *ExecEnv) attr(name string, value *string, export, readonly bool) error {
+	k := env.keyFor(name)
+	v, ok := env.vars.m[k]
+	if value != nil {
+		if v.ReadOnly {
+			return fmt.Errorf("%v: %w", name, ErrReadOnly)
+		}
+		v.Value = *value
+		v.unset = false
+		if env.Opts&AllExport != 0 {
+			v.Export = true
+		}
+	} else if !ok {
+		v.unset = true
+	}
+	v.Name = name
+	v.Export = v.Export || export
+	v.ReadOnly = v.ReadOnly || readonly
+	env.vars.set(k, v)
+	return nil
+}
+
+// Environ returns a copy of strings representing the environment for
+// an external utility in the form "key=value". It consists of the
+// exported variables only, and is sorted.
+func (env *ExecEnv) Environ() []string {
+	return env.environ()
 }
 
 // Walk walks the variables, calling fn for each.
 func (env *ExecEnv) Walk(fn func(Var)) {
-	for _, v := range env.vars {
-		fn(v)
+	for _, v := range env.vars.m {
+		if !v.unset {
+			fn(v)
+		}
 	}
 }
 
@@ -125,6 +350,16 @@ func (env *ExecEnv) isSpParam(s string) bool {
 	return false
 }
 
+// isName reports whether s satisfies XBD Name.
+func (env *ExecEnv) isName(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != ""
+}
+
 // isPosParam reports whether s matches the name of a positional
 // parameter.
 func (env *ExecEnv) isPosParam(s string) bool {
@@ -157,6 +392,22 @@ const (
 
 const optionString = "ae mCfn buv x"
 
+var optionNames = [...]string{
+	"allexport",
+	"errexit",
+	"ignoreeof",
+	"monitor",
+	"noclobber",
+	"noglob",
+	"noexec",
+	"nolog",
+	"notify",
+	"nounset",
+	"verbose",
+	"vi",
+	"xtrace",
+}
+
 func (o Option) String() string {
 	var b strings.Builder
 	for i := range len(optionString) + 1 {
@@ -171,6 +422,42 @@ func (o Option) String() string {
 	return b.String()
 }
 
+// cowMap is a map which is copied on the first write after it was shared
+// with a subshell environment.
+type cowMap[K comparable, V any] struct {
+	m      map[K]V
+	shared bool
+}
+
+// share returns a copy of c which shares the underlying map.
+func (c *cowMap[K, V]) share() cowMap[K, V] {
+	c.shared = true
+	return *c
+}
+
+func (c *cowMap[K, V]) set(k K, v V) {
+	c.own()
+	c.m[k] = v
+}
+
+func (c *cowMap[K, V]) delete(k K) {
+	if _, ok := c.m[k]; ok {
+		c.own()
+		delete(c.m, k)
+	}
+}
+
+// own copies the underlying map if it is shared.
+func (c *cowMap[K, V]) own() {
+	if c.shared {
+		c.m = maps.Clone(c.m)
+		c.shared = false
+	}
+	if c.m == nil {
+		c.m = make(map[K]V)
+	}
+}
+
 // Var represents a variable.
 type Var struct {
 	Name  string
@@ -178,4 +465,9 @@ type Var struct {
 
 	Export   bool
 	ReadOnly bool
+
+	unset bool
 }
+
+// ErrReadOnly indicates that the variable is readonly.
+var ErrReadOnly = errors.New("readonly variable")
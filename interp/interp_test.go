@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: interp_test.go
 //
-//   Copyright (c) 2021-2022 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -9,10 +9,15 @@
 package interp_test
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hattya/go.sh/interp"
@@ -90,13 +95,60 @@ func TestVar(t *testing.T) {
 		if v.Export {
 			export++
 		}
-		n++
+		if v.Name != "PPID" {
+			n++
+		}
 	})
 	if export != n {
 		t.Errorf("expected export == n; got export = %d, n = %d", export, n)
 	}
 }
 
+func TestVarAttr(t *testing.T) {
+	env, _, _ := newTestEnv(t)
+	for _, k := range []string{"FOO", "BAR", "BAZ", "QUX"} {
+		env.Unset(k)
+	}
+	if _, err := run(env, "export FOO=foo; readonly BAR=bar; BAZ=baz"); err != nil {
+		t.Fatal(err)
+	}
+	// readonly
+	if err := env.Set("BAR", "_"); !errors.Is(err, interp.ErrReadOnly) {
+		t.Errorf("expected %v, got %v", interp.ErrReadOnly, err)
+	}
+	if err := env.Unset("BAR"); !errors.Is(err, interp.ErrReadOnly) {
+		t.Errorf("expected %v, got %v", interp.ErrReadOnly, err)
+	}
+	if v, _ := env.Get("BAR"); v.Value != "bar" || !v.ReadOnly {
+		t.Errorf("unexpected variable: %#v", v)
+	}
+	// export
+	if err := env.Set("FOO", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := env.Get("FOO"); v.Value != "baz" || !v.Export {
+		t.Errorf("unexpected variable: %#v", v)
+	}
+	env.Opts |= interp.AllExport
+	if err := env.Set("QUX", "qux"); err != nil {
+		t.Fatal(err)
+	}
+	environ := env.Environ()
+	for _, s := range []string{"FOO=baz", "QUX=qux"} {
+		if !slices.Contains(environ, s) {
+			t.Errorf("expected %q in %q", s, environ)
+		}
+	}
+	for _, s := range []string{"BAR=bar", "BAZ=baz"} {
+		if slices.Contains(environ, s) {
+			t.Errorf("unexpected %q in %q", s, environ)
+		}
+	}
+	if !slices.IsSorted(environ) {
+		t.Errorf("expected sorted, got %q", environ)
+	}
+}
+
 func TestSpParam(t *testing.T) {
 	env := interp.NewExecEnv(name, "1")
 	for _, tt := range []struct {
@@ -109,6 +161,10 @@ func TestSpParam(t *testing.T) {
 		{"!", ""},
 		{"0", name},
 	} {
+		// subshell
+		if v, _ := env.Subshell().Get(tt.name); v.Value != tt.value {
+			t.Errorf("expected $%v = %q, got %q", tt.name, tt.value, v.Value)
+		}
 		// get
 		if v, _ := env.Get(tt.name); v.Value != tt.value {
 			t.Errorf("expected $%v = %q, got %q", tt.name, tt.value, v.Value)
@@ -121,6 +177,19 @@ func TestSpParam(t *testing.T) {
 	}
 }
 
+func TestPPID(t *testing.T) {
+	env := interp.NewExecEnv(name)
+	e := strconv.Itoa(os.Getppid())
+	for _, env := range []*interp.ExecEnv{env, env.Subshell()} {
+		switch v, _ := env.Get("PPID"); {
+		case v.Value != e:
+			t.Errorf("expected $PPID = %q, got %q", e, v.Value)
+		case v.Export:
+			t.Error("expected not exported")
+		}
+	}
+}
+
 func TestPosParam(t *testing.T) {
 	env := interp.NewExecEnv(name, "1")
 	// get
@@ -148,6 +217,95 @@ func TestPosParam(t *testing.T) {
 	}
 }
 
+func TestSubshell(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	if _, err := run(env, "FOO=foo; BAR=bar; f() { helper echo f; }; trap 'helper echo INT' INT; trap '' TERM"); err != nil {
+		t.Fatal(err)
+	}
+	state := func(env *interp.ExecEnv) string {
+		stdout.Reset()
+		if _, err := run(env, "helper echo $FOO ${BAR-unset} $-; f; trap; umask"); err != nil {
+			t.Fatal(err)
+		}
+		return stdout.String()
+	}
+	save := state(env)
+
+	sub := env.Subshell()
+	if g, e := state(sub), "foo bar\nf\ntrap -- 'helper echo INT' INT\ntrap -- '' TERM\n"; !strings.HasPrefix(g, e) {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	if _, err := run(sub, "FOO=baz; unset BAR; f() { helper echo g; }; set -f; trap - TERM; umask 077"); err != nil {
+		t.Fatal(err)
+	}
+	sub.Aliases["helper"] = "false"
+	if g, e := state(env), save; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	// the parent modifies shared variables and functions
+	sub = env.Subshell()
+	if _, err := run(env, "FOO=qux; f() { helper echo h; }"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := state(sub), "foo bar\nf\n"; !strings.HasPrefix(g, e) {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+func TestChdir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := make([]string, 4)
+	for i := range dirs {
+		dir := t.TempDir()
+		if dir, err = filepath.EvalSymlinks(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, "d"), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		dirs[i] = dir
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(dirs))
+	for _, dir := range dirs {
+		go func() {
+			defer wg.Done()
+
+			env := interp.NewExecEnv(name)
+			if err := env.Chdir(dir); err != nil {
+				t.Error(err)
+				return
+			}
+			stdout := new(strings.Builder)
+			env.Stdout = stdout
+			if _, err := run(env, "cd d; echo foo >f; cd ..; echo */*; pwd; echo $OLDPWD"); err != nil {
+				t.Error(err)
+				return
+			}
+			if g, e := filepath.ToSlash(stdout.String()), filepath.ToSlash(fmt.Sprintf("d/f\n%v\n%v\n", dir, filepath.Join(dir, "d"))); g != e {
+				t.Errorf("expected %q, got %q", e, g)
+			}
+			if g, e := env.Getwd(), dir; g != e {
+				t.Errorf("expected %q, got %q", e, g)
+			}
+			if err := env.Chdir("f"); err == nil {
+				t.Error("expected error")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if g, err := os.Getwd(); err != nil {
+		t.Fatal(err)
+	} else if g != wd {
+		t.Errorf("expected %q, got %q", wd, g)
+	}
+}
+
 func pushd(path string) (func() error, error) {
 	wd, err := os.Getwd()
 	popd := func() error {
@@ -1,7 +1,7 @@
 //
 // go.sh/interp :: interp_unix.go
 //
-//   Copyright (c) 2021-2023 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -10,6 +10,87 @@
 
 package interp
 
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+const pathSeps = "/"
+
 func (env *ExecEnv) keyFor(name string) string {
 	return name
 }
+
+func (env *ExecEnv) findExecutable(path string) (string, error) {
+	switch fi, err := env.stat(path); {
+	case err != nil:
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", os.ErrPermission
+	case fi.IsDir() || fi.Mode()&0o111 == 0:
+		return "", os.ErrPermission
+	}
+	return path, nil
+}
+
+func access(path string, _ os.FileInfo, mode uint32) bool {
+	return syscall.Access(path, mode) == nil
+}
+
+func defaultPath() string {
+	return "/bin:/usr/bin"
+}
+
+func getUmask() int {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return mask
+}
+
+func setUmask(mask int) {
+	syscall.Umask(mask)
+}
+
+func setpgid(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+func exitStatus(ps *os.ProcessState) int {
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok {
+		return waitStatus(ws)
+	}
+	return ps.ExitCode()
+}
+
+func waitStatus(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// users returns the login names in the user database.
+func users() []string {
+	b, err := os.ReadFile("/etc/passwd")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for l := range strings.Lines(string(b)) {
+		if name, _, ok := strings.Cut(l, ":"); ok && name != "" && name[0] != '#' && name[0] != '+' && name[0] != '-' {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -1,15 +1,88 @@
 //
 // go.sh/interp :: interp_windows.go
 //
-//   Copyright (c) 2021 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
 
 package interp
 
-import "strings"
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const pathSeps = `\/:`
 
 func (env *ExecEnv) keyFor(name string) string {
 	return strings.ToUpper(name)
 }
+
+func (env *ExecEnv) findExecutable(path string) (string, error) {
+	var exts []string
+	if v, set := env.Get("PATHEXT"); set && v.Value != "" {
+		for _, e := range filepath.SplitList(strings.ToLower(v.Value)) {
+			if e != "" {
+				if e[0] != '.' {
+					e = "." + e
+				}
+				exts = append(exts, e)
+			}
+		}
+	} else {
+		exts = []string{".com", ".exe", ".bat", ".cmd"}
+	}
+
+	stat := func(path string) bool {
+		fi, err := env.stat(path)
+		return err == nil && !fi.IsDir()
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext != "" {
+		for _, e := range exts {
+			if e == ext && stat(path) {
+				return path, nil
+			}
+		}
+	}
+	for _, e := range exts {
+		if stat(path + e) {
+			return path + e, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func access(_ string, fi os.FileInfo, mode uint32) bool {
+	return mode&2 == 0 || fi.Mode()&0o200 != 0
+}
+
+func defaultPath() string {
+	root := os.Getenv("SystemRoot")
+	return filepath.Join(root, "System32") + string(filepath.ListSeparator) + root
+}
+
+func getUmask() int {
+	return 0
+}
+
+func setUmask(int) {}
+
+func setpgid(*exec.Cmd) {}
+
+func exitStatus(ps *os.ProcessState) int {
+	return ps.ExitCode()
+}
+
+func isBrokenPipe(err error) bool {
+	// ERROR_NO_DATA is returned when the read end of the pipe is closed
+	return errors.Is(err, syscall.ERROR_BROKEN_PIPE) || errors.Is(err, syscall.Errno(232))
+}
+
+func users() []string {
+	return nil
+}
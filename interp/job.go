@@ -0,0 +1,870 @@
+//
+// go.sh/interp :: job.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/hattya/go.sh/ast"
+	"github.com/hattya/go.sh/printer"
+)
+
+var (
+	errNoSuchJob    = errors.New("no such job")
+	errAmbiguousJob = errors.New("ambiguous job")
+)
+
+// jobState represents the state of a job.
+type jobState int
+
+const (
+	running jobState = iota
+	stopped
+	done
+)
+
+// job represents an asynchronous list, or a pipeline which was stopped
+// while it was executed in the foreground.
+type job struct {
+	id    int // job number
+	pid   int // process ID reported by $!
+	cmd   string
+	async bool
+	done  chan struct{}
+
+	mu       sync.Mutex
+	pgid     int
+	procs    []*process
+	fg       bool
+	tty      *os.File
+	state    jobState
+	status   int
+	sig      syscall.Signal
+	changed  chan struct{}
+	notified bool
+	t        *jobTable
+}
+
+// process represents a process of a job.
+type process struct {
+	pid      int
+	upstream bool
+	state    jobState
+	status   int
+	sig      syscall.Signal
+}
+
+func newJob(cmd ast.Command, async bool) *job {
+	var b strings.Builder
+	printer.Fprint(&b, cmd)
+	return &job{
+		cmd:     b.String(),
+		async:   async,
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// wait waits for the job to complete, and returns its exit status.
+func (j *job) wait(ctx context.Context) (int, error) {
+	select {
+	case <-j.done:
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return j.status, nil
+	case <-ctx.Done():
+		return 1, ctx.Err()
+	}
+}
+
+// waitState waits for the job to complete or stop, and returns its state
+// and exit status.
+func (j *job) waitState(ctx context.Context) (jobState, int, error) {
+	for {
+		j.mu.Lock()
+		state, status, changed := j.state, j.status, j.changed
+		j.mu.Unlock()
+		if state != running {
+			return state, status, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return state, 1, ctx.Err()
+		}
+	}
+}
+
+// setState changes the state of the job. It must be called with j.mu
+// held, and reports whether the state was changed.
+func (j *job) setState(state jobState, status int) bool {
+	if j.state == done || (j.state == state && state == running) {
+		return false
+	}
+	j.state = state
+	j.status = status
+	j.notified = false
+	close(j.changed)
+	j.changed = make(chan struct{})
+	return true
+}
+
+// notify notifies the job table of the state change of the job, and then
+// the waiters of the job if it was completed.
+func (j *job) notify() {
+	j.table().changed(j)
+	j.mu.Lock()
+	state := j.state
+	j.mu.Unlock()
+	if state == done {
+		close(j.done)
+	}
+}
+
+// finish marks the asynchronous list as completed.
+func (j *job) finish(status int) {
+	j.mu.Lock()
+	ok := j.setState(done, status)
+	j.mu.Unlock()
+	if ok {
+		j.notify()
+	}
+}
+
+// update updates the state of the process, and the state of the job
+// which is derived from its processes.
+func (j *job) update(p *process, state jobState, status int, sig syscall.Signal) {
+	j.mu.Lock()
+	p.state, p.status, p.sig = state, status, sig
+	state, status, sig = running, 0, 0
+	n := 0
+	for _, p := range j.procs {
+		switch p.state {
+		case stopped:
+			state, status, sig = stopped, p.status, p.sig
+		case done:
+			n++
+			if !p.upstream {
+				status = p.status
+			}
+		}
+	}
+	if state != stopped && !j.async && n == len(j.procs) {
+		state = done
+	}
+	var ok bool
+	switch {
+	case state != stopped && j.async:
+		ok = j.setState(running, 0)
+	default:
+		j.sig = sig
+		ok = j.setState(state, status)
+	}
+	j.mu.Unlock()
+	if ok {
+		j.notify()
+	}
+}
+
+// cont continues the stopped processes of the job in the foreground or
+// the background.
+func (j *job) cont(fg bool, tty *os.File) error {
+	j.mu.Lock()
+	j.fg = fg
+	j.tty = tty
+	pgid := j.pgid
+	for _, p := range j.procs {
+		if p.state == stopped {
+			p.state = running
+		}
+	}
+	j.setState(running, 0)
+	j.mu.Unlock()
+	if pgid == 0 {
+		return nil
+	}
+	if fg && tty != nil {
+		if err := tcsetpgrp(tty, pgid); err != nil {
+			return err
+		}
+	}
+	return killpgCont(pgid)
+}
+
+// signal sends the signal to the process group of the job, or to the
+// process reported by $! if the job does not have a process group.
+func (j *job) signal(sig syscall.Signal) error {
+	j.mu.Lock()
+	pgid := j.pgid
+	j.mu.Unlock()
+	if pgid == 0 {
+		return kill(j.pid, sig)
+	}
+	return kill(-pgid, sig)
+}
+
+// table returns the job table which the job belongs to.
+func (j *job) table() *jobTable {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.t
+}
+
+// jobTable is a table of the jobs known to the shell execution
+// environment. It is safe for concurrent use.
+type jobTable struct {
+	mu     sync.Mutex
+	jobs   []*job
+	order  []*job // the current job comes first
+	notify io.Writer
+	parent *jobTable
+}
+
+// add adds the job to the table, and makes it the current job.
+func (t *jobTable) add(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if j.id == 0 {
+		j.id = 1
+		for _, x := range t.jobs {
+			j.id = max(j.id, x.id+1)
+		}
+		t.jobs = append(t.jobs, j)
+		j.mu.Lock()
+		j.t = t
+		j.mu.Unlock()
+	}
+	t.current(j)
+}
+
+// current makes the job the current job. It must be called with t.mu
+// held.
+func (t *jobTable) current(j *job) {
+	if i := slices.Index(t.order, j); i != -1 {
+		t.order = slices.Delete(t.order, i, i+1)
+	}
+	t.order = slices.Insert(t.order, 0, j)
+}
+
+// get returns the job of the process ID, or nil if it is not known.
+func (t *jobTable) get(pid int) *job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, j := range t.jobs {
+		if j.pid == pid {
+			return j
+		}
+	}
+	return nil
+}
+
+// find returns the job specified by the job ID or the process ID.
+func (t *jobTable) find(s string) (*job, error) {
+	if !strings.HasPrefix(s, "%") {
+		pid, err := strconv.Atoi(s)
+		if err != nil || pid <= 0 {
+			return nil, errNoSuchJob
+		}
+		if j := t.get(pid); j != nil {
+			return j, nil
+		}
+		return nil, errNoSuchJob
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch s = s[1:]; s {
+	case "", "%", "+":
+		if len(t.order) > 0 {
+			return t.order[0], nil
+		}
+		return nil, errNoSuchJob
+	case "-":
+		if len(t.order) > 1 {
+			return t.order[1], nil
+		}
+		return nil, errNoSuchJob
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		for _, j := range t.jobs {
+			if j.id == n {
+				return j, nil
+			}
+		}
+		return nil, errNoSuchJob
+	}
+	match := strings.HasPrefix
+	if strings.HasPrefix(s, "?") {
+		s = s[1:]
+		match = strings.Contains
+	}
+	var rv *job
+	for _, j := range t.jobs {
+		if match(j.cmd, s) {
+			if rv != nil {
+				return nil, errAmbiguousJob
+			}
+			rv = j
+		}
+	}
+	if rv == nil {
+		return nil, errNoSuchJob
+	}
+	return rv, nil
+}
+
+// list returns a copy of the jobs in the table.
+func (t *jobTable) list() []*job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.jobs)
+}
+
+// remove removes the job from the table.
+func (t *jobTable) remove(j *job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drop(j)
+}
+
+// setNotify sets the writer to which the state changes of the jobs are
+// written asynchronously. If w is nil, they are not written.
+func (t *jobTable) setNotify(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.notify = w
+}
+
+// changed is called when the state of the job was changed.
+func (t *jobTable) changed(j *job) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j.mu.Lock()
+	state := j.state
+	j.mu.Unlock()
+	if state == stopped {
+		t.current(j)
+	}
+	if t.notify != nil && state != running {
+		t.report(t.notify, j, false)
+	}
+}
+
+// report writes the state of the job in the format of the jobs utility.
+// It must be called with t.mu held, and reports whether the job was
+// completed.
+func (t *jobTable) report(w io.Writer, j *job, long bool) bool {
+	cur := ' '
+	switch slices.Index(t.order, j) {
+	case 0:
+		cur = '+'
+	case 1:
+		cur = '-'
+	}
+	j.mu.Lock()
+	var state string
+	switch j.state {
+	case running:
+		state = "Running"
+	case stopped:
+		state = "Stopped"
+		if name := sigName(j.sig); name != "" {
+			state += " (SIG" + name + ")"
+		}
+	case done:
+		state = "Done"
+		if j.status != 0 {
+			state += "(" + strconv.Itoa(j.status) + ")"
+		}
+	}
+	pgid := j.pgid
+	if pgid == 0 {
+		pgid = j.pid
+	}
+	j.notified = true
+	completed := j.state == done
+	j.mu.Unlock()
+
+	if long {
+		fmt.Fprintf(w, "[%v] %c %v %v %v\n", j.id, cur, pgid, state, j.cmd)
+	} else {
+		fmt.Fprintf(w, "[%v] %c %v %v\n", j.id, cur, state, j.cmd)
+	}
+	return completed
+}
+
+// drop removes the job from the table. It must be called with t.mu held.
+func (t *jobTable) drop(j *job) {
+	t.jobs = slices.DeleteFunc(t.jobs, func(x *job) bool { return x == j })
+	t.order = slices.DeleteFunc(t.order, func(x *job) bool { return x == j })
+}
+
+// ReportJobs writes the state of the jobs which was changed since the
+// last report to the standard error, and removes the completed jobs
+// which were reported. It does nothing unless the Monitor option is set.
+//
+// It should be called before the shell writes a prompt.
+func (env *ExecEnv) ReportJobs() {
+	if env.Opts&Monitor == 0 {
+		return
+	}
+	t := env.jobs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, j := range slices.Clone(t.jobs) {
+		j.mu.Lock()
+		notified, state := j.notified, j.state
+		j.mu.Unlock()
+		if !notified && state != running {
+			t.report(env.Stderr, j, false)
+		}
+		if state == done {
+			t.drop(j)
+		}
+	}
+}
+
+// setNotify updates the destination of the asynchronous notification.
+func (env *ExecEnv) setNotify() {
+	var w io.Writer
+	if env.Opts&(Monitor|Notify) == Monitor|Notify {
+		w = env.Stderr
+	}
+	env.jobs.setNotify(w)
+}
+
+// lastJobID is the last ID assigned to an asynchronous list which is not
+// a single external utility. The IDs begin above the maximum process ID
+// of Linux (PID_MAX_LIMIT) to avoid confusion with the process IDs.
+var lastJobID atomic.Int64
+
+func init() {
+	lastJobID.Store(1 << 22)
+}
+
+// runAsync executes an AND-OR list asynchronously in a subshell
+// environment.
+//
+// The process ID of the asynchronous list is the one of the utility if
+// it is a simple command which executes an external utility by
+// DefaultExecHandler, otherwise a unique ID is assigned to it.
+//
+// If the Monitor option is set, the utilities executed by the
+// asynchronous list are placed in a new process group.
+func (env *ExecEnv) runAsync(ctx context.Context, cmd *ast.AndOrList) (int, error) {
+	j := newJob(cmd, true)
+	sub := env.Subshell()
+	sub.upstream = false
+	var stdin io.Closer
+	if env.Opts&Monitor == 0 {
+		sub.job = nil
+		// the standard input is assigned to /dev/null before any
+		// explicit redirections
+		if f, err := os.Open(os.DevNull); err == nil {
+			sub.Stdin = f
+			stdin = f
+		}
+	} else {
+		sub.job = j
+	}
+	var pid chan int
+	if _, ok := cmd.Pipeline.Cmd.Expr.(*ast.SimpleCmd); ok && len(cmd.List) == 0 && len(cmd.Pipeline.List) == 0 {
+		pid = make(chan int, 1)
+		sub.started = func(n int) {
+			pid <- n
+		}
+	}
+
+	go func() {
+		status, err := sub.runAndOr(ctx, cmd)
+		status, _ = sub.exit(ctx, status, err)
+		if stdin != nil {
+			stdin.Close()
+		}
+		j.finish(status)
+	}()
+	if pid != nil {
+		select {
+		case j.pid = <-pid:
+		case <-j.done:
+		}
+	}
+	if j.pid == 0 {
+		j.pid = int(lastJobID.Add(1))
+	}
+	env.setNotify()
+	env.jobs.add(j)
+	env.bgPid = j.pid
+	if env.Opts&Monitor != 0 {
+		fmt.Fprintf(env.Stderr, "[%v] %v\n", j.id, j.pid)
+	}
+	env.status = 0
+	return 0, nil
+}
+
+// runJob executes a pipeline in the foreground. If the Monitor option is
+// set, the utilities executed by the pipeline are placed in a new process
+// group, and the pipeline becomes a job when it was stopped.
+func (env *ExecEnv) runJob(ctx context.Context, cmd *ast.Pipeline) (int, error) {
+	if env.Opts&Monitor == 0 || env.job != nil {
+		return env.runPipeline(ctx, cmd)
+	}
+
+	j := newJob(cmd, false)
+	j.fg = true
+	j.tty = terminal(env.Stdin)
+	env.job = j
+	status, err := env.runPipeline(ctx, cmd)
+	env.job = nil
+
+	j.mu.Lock()
+	pgid, state := j.pgid, j.state
+	j.mu.Unlock()
+	if pgid != 0 && j.tty != nil {
+		tcsetpgrp(j.tty, getpgrp())
+	}
+	if state == stopped {
+		env.setNotify()
+		env.jobs.add(j)
+		t := env.jobs
+		t.mu.Lock()
+		t.report(env.Stderr, j, false)
+		t.mu.Unlock()
+	}
+	return status, err
+}
+
+// start reports the process ID of the utility started by the simple
+// command of the asynchronous list, or 0 if the simple command does not
+// execute an external utility.
+func (env *ExecEnv) start(pid int) {
+	if env.started != nil {
+		env.started(pid)
+		env.started = nil
+	}
+}
+
+// bgBuiltin continues the stopped jobs in the background.
+func bgBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	if env.Opts&Monitor == 0 {
+		fmt.Fprintln(env.Stderr, "bg: no job control")
+		return 1, nil
+	}
+	args = args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		args = []string{"%+"}
+	}
+	status := 0
+	for _, s := range args {
+		j, err := env.jobs.find(s)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "bg: %v: %v\n", s, err)
+			status = 1
+			continue
+		}
+		if err := j.cont(false, nil); err != nil {
+			fmt.Fprintf(env.Stderr, "bg: %v: %v\n", s, err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(env.Stdout, "[%v] %v\n", j.id, j.cmd)
+	}
+	return status, nil
+}
+
+// fgBuiltin continues the job in the foreground, and waits for it.
+func fgBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	if env.Opts&Monitor == 0 {
+		fmt.Fprintln(env.Stderr, "fg: no job control")
+		return 1, nil
+	}
+	args = args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	var s string
+	switch len(args) {
+	case 0:
+		s = "%+"
+	case 1:
+		s = args[0]
+	default:
+		fmt.Fprintln(env.Stderr, "fg: too many arguments")
+		return 2, nil
+	}
+	j, err := env.jobs.find(s)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "fg: %v: %v\n", s, err)
+		return 1, nil
+	}
+
+	fmt.Fprintln(env.Stdout, j.cmd)
+	tty := terminal(env.Stdin)
+	if err := j.cont(true, tty); err != nil {
+		fmt.Fprintf(env.Stderr, "fg: %v: %v\n", s, err)
+		return 1, nil
+	}
+	state, status, err := j.waitState(ctx)
+	j.mu.Lock()
+	j.fg = false
+	pgid := j.pgid
+	j.mu.Unlock()
+	if pgid != 0 && tty != nil {
+		tcsetpgrp(tty, getpgrp())
+	}
+	switch {
+	case err != nil:
+		return 1, err
+	case state == stopped:
+		env.setNotify()
+		env.jobs.add(j)
+		t := env.jobs
+		t.mu.Lock()
+		t.report(env.Stderr, j, false)
+		t.mu.Unlock()
+	default:
+		env.jobs.remove(j)
+	}
+	return status, nil
+}
+
+// jobsBuiltin writes the state of the jobs.
+func jobsBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	var long, pids bool
+	args = args[1:]
+Options:
+	for len(args) > 0 {
+		switch args[0] {
+		case "-l":
+			long = true
+		case "-p":
+			pids = true
+		case "--":
+			args = args[1:]
+			break Options
+		default:
+			if len(args[0]) > 1 && args[0][0] == '-' {
+				fmt.Fprintf(env.Stderr, "jobs: %v: invalid option\n", args[0])
+				return 2, nil
+			}
+			break Options
+		}
+		args = args[1:]
+	}
+
+	// a subshell environment reports the jobs of its parent until it
+	// creates a job
+	t := env.jobs
+	for t.parent != nil && len(t.list()) == 0 {
+		t = t.parent
+	}
+	var jobs []*job
+	status := 0
+	if len(args) == 0 {
+		jobs = t.list()
+	} else {
+		for _, s := range args {
+			j, err := t.find(s)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "jobs: %v: %v\n", s, err)
+				status = 1
+				continue
+			}
+			jobs = append(jobs, j)
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, j := range jobs {
+		if pids {
+			j.mu.Lock()
+			id := j.pgid
+			j.mu.Unlock()
+			if id == 0 {
+				id = j.pid
+			}
+			fmt.Fprintln(env.Stdout, id)
+		} else if t.report(env.Stdout, j, long) {
+			t.drop(j)
+		}
+	}
+	return status, nil
+}
+
+// killBuiltin sends a signal to the processes or the jobs.
+func killBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	sig := syscall.SIGTERM
+	args = args[1:]
+	switch {
+	case len(args) == 0:
+		fmt.Fprintln(env.Stderr, "kill: missing operand")
+		return 2, nil
+	case args[0] == "-l":
+		if len(args) == 1 {
+			var names []string
+			for _, s := range signals {
+				names = append(names, s.name)
+			}
+			fmt.Fprintln(env.Stdout, strings.Join(names, " "))
+			return 0, nil
+		}
+		status := 0
+		for _, s := range args[1:] {
+			n, err := strconv.Atoi(s)
+			if err == nil && n > 128 {
+				n -= 128
+			}
+			name := sigName(syscall.Signal(n))
+			if err != nil || name == "" {
+				fmt.Fprintf(env.Stderr, "kill: %v: invalid signal\n", s)
+				status = 1
+				continue
+			}
+			fmt.Fprintln(env.Stdout, name)
+		}
+		return status, nil
+	case args[0] == "-s":
+		if len(args) == 1 {
+			fmt.Fprintln(env.Stderr, "kill: -s: missing signal")
+			return 2, nil
+		}
+		var ok bool
+		if sig, ok = parseSignal(args[1]); !ok {
+			fmt.Fprintf(env.Stderr, "kill: %v: invalid signal\n", args[1])
+			return 1, nil
+		}
+		args = args[2:]
+	case args[0] == "--":
+		args = args[1:]
+	case len(args[0]) > 1 && args[0][0] == '-':
+		var ok bool
+		if sig, ok = parseSignal(args[0][1:]); !ok {
+			fmt.Fprintf(env.Stderr, "kill: %v: invalid signal\n", args[0][1:])
+			return 1, nil
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	status := 0
+	for _, s := range args {
+		var err error
+		if strings.HasPrefix(s, "%") {
+			var j *job
+			if j, err = env.jobs.find(s); err == nil {
+				err = j.signal(sig)
+			}
+		} else {
+			var pid int
+			if pid, err = strconv.Atoi(s); err != nil {
+				err = errors.New("invalid process id")
+			} else {
+				err = kill(pid, sig)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "kill: %v: %v\n", s, err)
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+// parseSignal parses s as a signal name or a signal number.
+func parseSignal(s string) (syscall.Signal, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n == 0 || sigName(syscall.Signal(n)) != "" {
+			return syscall.Signal(n), true
+		}
+		return 0, false
+	}
+	s = strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	for _, x := range signals {
+		if x.name == s {
+			return x.sig, true
+		}
+	}
+	return 0, false
+}
+
+// sigName returns the name of the signal without the "SIG" prefix.
+func sigName(sig syscall.Signal) string {
+	for _, x := range signals {
+		if x.sig == sig {
+			return x.name
+		}
+	}
+	return ""
+}
+
+// waitBuiltin waits for the jobs to complete.
+func waitBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	args = args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	// wait is interrupted by a signal for which the trap is set
+	ctx, cancel := env.interruptible(ctx)
+	defer cancel()
+	if len(args) == 0 {
+		for _, j := range env.jobs.list() {
+			if _, err := j.wait(ctx); err != nil {
+				if status, ok := interrupted(ctx); ok {
+					return status, nil
+				}
+				return 1, err
+			}
+			env.jobs.remove(j)
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, s := range args {
+		if !strings.HasPrefix(s, "%") {
+			if pid, err := strconv.Atoi(s); err != nil || pid <= 0 {
+				fmt.Fprintf(env.Stderr, "wait: %v: invalid process id\n", s)
+				status = 1
+				continue
+			}
+		}
+		j, err := env.jobs.find(s)
+		if err != nil {
+			// unknown process ID
+			status = 127
+			continue
+		}
+		if status, err = j.wait(ctx); err != nil {
+			if status, ok := interrupted(ctx); ok {
+				return status, nil
+			}
+			return 1, err
+		}
+		env.jobs.remove(j)
+	}
+	return status, nil
+}
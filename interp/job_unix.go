@@ -0,0 +1,180 @@
+//
+// go.sh/interp :: job_unix.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+//go:build unix
+
+package interp
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+var signals = []struct {
+	name string
+	sig  syscall.Signal
+}{
+	{"HUP", syscall.SIGHUP},
+	{"INT", syscall.SIGINT},
+	{"QUIT", syscall.SIGQUIT},
+	{"ILL", syscall.SIGILL},
+	{"TRAP", syscall.SIGTRAP},
+	{"ABRT", syscall.SIGABRT},
+	{"BUS", syscall.SIGBUS},
+	{"FPE", syscall.SIGFPE},
+	{"KILL", syscall.SIGKILL},
+	{"USR1", syscall.SIGUSR1},
+	{"SEGV", syscall.SIGSEGV},
+	{"USR2", syscall.SIGUSR2},
+	{"PIPE", syscall.SIGPIPE},
+	{"ALRM", syscall.SIGALRM},
+	{"TERM", syscall.SIGTERM},
+	{"CHLD", syscall.SIGCHLD},
+	{"CONT", syscall.SIGCONT},
+	{"STOP", syscall.SIGSTOP},
+	{"TSTP", syscall.SIGTSTP},
+	{"TTIN", syscall.SIGTTIN},
+	{"TTOU", syscall.SIGTTOU},
+	{"URG", syscall.SIGURG},
+	{"XCPU", syscall.SIGXCPU},
+	{"XFSZ", syscall.SIGXFSZ},
+	{"VTALRM", syscall.SIGVTALRM},
+	{"PROF", syscall.SIGPROF},
+	{"WINCH", syscall.SIGWINCH},
+	{"SYS", syscall.SIGSYS},
+}
+
+func kill(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+func killpgCont(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGCONT)
+}
+
+func getpgrp() int {
+	return syscall.Getpgrp()
+}
+
+// terminal returns r if it is the controlling terminal of the process.
+func terminal(r io.Reader) *os.File {
+	if f, ok := r.(*os.File); ok {
+		if _, err := tcgetpgrp(f); err == nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func tcgetpgrp(f *os.File) (int, error) {
+	var pgid int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgid))); errno != 0 {
+		return 0, errno
+	}
+	return int(pgid), nil
+}
+
+// tcsetpgrp makes the process group the foreground process group of the
+// terminal. SIGTTOU is ignored temporarily because the shell may be in
+// a background process group, and no processes are forked meanwhile to
+// prevent them from inheriting it.
+func tcsetpgrp(f *os.File, pgid int) error {
+	syscall.ForkLock.Lock()
+	defer syscall.ForkLock.Unlock()
+	if !signal.Ignored(syscall.SIGTTOU) {
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+	}
+	id := int32(pgid)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&id))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// exec starts the utility in the process group of the job, and waits for
+// it. If the job is not an asynchronous list, it returns when the utility
+// was stopped, and the utility is waited for in the background.
+func (j *job) exec(ctx context.Context, c *exec.Cmd, cmd *Cmd) (int, error) {
+	j.mu.Lock()
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+		Pgid:    j.pgid,
+	}
+	if j.fg && j.tty != nil {
+		c.SysProcAttr.Foreground = true
+		c.SysProcAttr.Ctty = int(j.tty.Fd())
+	}
+	if ctx.Done() != nil {
+		c.Cancel = func() error {
+			return j.signal(syscall.SIGKILL)
+		}
+		c.WaitDelay = waitDelay
+	}
+	if err := c.Start(); err != nil {
+		j.mu.Unlock()
+		return execStatus(ctx, cmd, err)
+	}
+	if j.pgid == 0 {
+		j.pgid = c.Process.Pid
+	}
+	p := &process{
+		pid:      c.Process.Pid,
+		upstream: cmd.upstream,
+	}
+	j.procs = append(j.procs, p)
+	j.mu.Unlock()
+	if cmd.started != nil {
+		cmd.started(p.pid)
+	}
+
+	status, exited := j.wait4(c, p, !j.async)
+	switch {
+	case !exited:
+		go j.wait4(c, p, false)
+	case ctx.Err() != nil:
+		return 1, ctx.Err()
+	}
+	return status, nil
+}
+
+// wait4 waits for the process to exit, and updates its state. If stop is
+// true, it returns when the process was stopped, and reports whether the
+// process exited.
+func (j *job) wait4(c *exec.Cmd, p *process, stop bool) (int, bool) {
+	for {
+		var ws syscall.WaitStatus
+		switch _, err := syscall.Wait4(p.pid, &ws, syscall.WUNTRACED|syscall.WCONTINUED, nil); {
+		case err == syscall.EINTR:
+		case err != nil:
+			c.Wait()
+			j.update(p, done, 1, 0)
+			return 1, true
+		case ws.Stopped():
+			status := 128 + int(ws.StopSignal())
+			j.update(p, stopped, status, ws.StopSignal())
+			if stop {
+				return status, false
+			}
+		case ws.Continued():
+			j.update(p, running, 0, 0)
+		default:
+			// the process was reaped, and Wait only waits for the
+			// completion of copying I/O
+			c.Wait()
+			status := waitStatus(ws)
+			j.update(p, done, status, 0)
+			return status, true
+		}
+	}
+}
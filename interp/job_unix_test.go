@@ -0,0 +1,143 @@
+//
+// go.sh/interp :: job_unix_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+//go:build unix
+
+package interp_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var jobsTests = []struct {
+	src    string
+	stdout string
+	stderr string
+	status int
+}{
+	{"helper sleep 10000 & jobs; kill %1; wait %1", "[1] + Running helper sleep 10000 &\n", "[1] $!\n", 143},
+	{"helper sleep 10000 & helper sleep 10000 & jobs; kill %- %+; wait", "[1] - Running helper sleep 10000 &\n[2] + Running helper sleep 10000 &\n", "[1] $1\n[2] $!\n", 0},
+	{"helper sleep 10000 & jobs -p; jobs -l %1; kill %?sleep; wait %1", "$!\n[1] + $! Running helper sleep 10000 &\n", "[1] $!\n", 143},
+	{"helper sleep 10000 & X=$!; kill $(jobs -p); wait $X", "", "[1] $!\n", 143},
+	{"helper sleep 10000 & kill -s KILL %?sleep; wait $!", "", "[1] $!\n", 137},
+	{"helper exit 0 & helper exit 0 & kill %?exit", "", "[1] $1\n[2] $!\nkill: %?exit: ambiguous job\n", 1},
+	{"set -b; helper exit 3 & wait $!", "", "[1] $!\n[1] + Done(3) helper exit 3 &\n", 3},
+	{"{ helper exit 3; } & wait; jobs", "", "[1] $!\n", 0},
+	{"fg", "", "fg: %+: no such job\n", 1},
+	{"bg %1", "", "bg: %1: no such job\n", 1},
+	{"jobs %1", "", "jobs: %1: no such job\n", 1},
+}
+
+func TestJobs(t *testing.T) {
+	for _, tt := range jobsTests {
+		env, stdout, stderr := newMonitorEnv(t)
+		status, err := run(env, tt.src)
+		var pids []string
+		if v, set := env.Get("!"); set {
+			pids = append(pids, "$!", v.Value)
+			if s := stderr.String(); strings.HasPrefix(s, "[1] ") {
+				pids = append(pids, "$1", s[4:strings.IndexByte(s, '\n')])
+			}
+		}
+		r := strings.NewReplacer(pids...)
+		h := strings.NewReplacer(env.Aliases["helper"], "helper")
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case h.Replace(stdout.String()) != r.Replace(tt.stdout):
+			t.Errorf("%q: expected %q, got %q", tt.src, r.Replace(tt.stdout), h.Replace(stdout.String()))
+		case h.Replace(stderr.String()) != r.Replace(tt.stderr):
+			t.Errorf("%q: expected %q, got %q", tt.src, r.Replace(tt.stderr), h.Replace(stderr.String()))
+		}
+	}
+}
+
+func TestJobControl(t *testing.T) {
+	env, stdout, stderr := newMonitorEnv(t)
+	h := strings.NewReplacer(env.Aliases["helper"], "helper")
+	// stopped in the foreground
+	src := fmt.Sprintf("helper kill %v", int(syscall.SIGSTOP))
+	switch status, err := run(env, src); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 128+int(syscall.SIGSTOP):
+		t.Errorf("expected %v, got %v", 128+int(syscall.SIGSTOP), status)
+	}
+	if g, e := h.Replace(stderr.String()), "[1] + Stopped (SIGSTOP) "+src+"\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	// continued in the foreground
+	stderr.Reset()
+	switch status, err := run(env, "fg"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 0:
+		t.Errorf("expected 0, got %v", status)
+	}
+	if g, e := h.Replace(stdout.String()), src+"\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	// stopped and continued in the background
+	stdout.Reset()
+	if _, err := run(env, "helper sleep 10000 & kill -STOP $!"); err != nil {
+		t.Fatal(err)
+	}
+	poll := func(e string) {
+		t.Helper()
+		for range 100 {
+			stdout.Reset()
+			if _, err := run(env, "jobs"); err != nil {
+				t.Fatal(err)
+			}
+			if h.Replace(stdout.String()) == e {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Errorf("expected %q, got %q", e, h.Replace(stdout.String()))
+	}
+	poll("[1] + Stopped (SIGSTOP) helper sleep 10000 &\n")
+	stdout.Reset()
+	if _, err := run(env, "bg"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := h.Replace(stdout.String()), "[1] helper sleep 10000 &\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+	poll("[1] + Running helper sleep 10000 &\n")
+	switch status, err := run(env, "kill %1; wait"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 0:
+		t.Errorf("expected 0, got %v", status)
+	}
+}
+
+func newMonitorEnv(t *testing.T) (env *interp.ExecEnv, stdout, stderr *strings.Builder) {
+	t.Helper()
+
+	env, stdout, stderr = newTestEnv(t)
+	env.Opts |= interp.Monitor
+	// the standard input is shared by the jobs
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	env.Stdin = f
+	return
+}
@@ -0,0 +1,87 @@
+//
+// go.sh/interp :: job_windows.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var signals = []struct {
+	name string
+	sig  syscall.Signal
+}{
+	{"HUP", syscall.SIGHUP},
+	{"INT", syscall.SIGINT},
+	{"QUIT", syscall.SIGQUIT},
+	{"ILL", syscall.SIGILL},
+	{"TRAP", syscall.SIGTRAP},
+	{"ABRT", syscall.SIGABRT},
+	{"BUS", syscall.SIGBUS},
+	{"FPE", syscall.SIGFPE},
+	{"KILL", syscall.SIGKILL},
+	{"SEGV", syscall.SIGSEGV},
+	{"PIPE", syscall.SIGPIPE},
+	{"ALRM", syscall.SIGALRM},
+	{"TERM", syscall.SIGTERM},
+}
+
+var errNotSupported = errors.New("not supported")
+
+func kill(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return errNotSupported
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer p.Release()
+
+	switch sig {
+	case 0:
+		return nil
+	case syscall.SIGKILL, syscall.SIGTERM:
+		return p.Kill()
+	}
+	return errNotSupported
+}
+
+func killpgCont(int) error {
+	return errNotSupported
+}
+
+func getpgrp() int {
+	return 0
+}
+
+// terminal always returns nil, because job control is not supported.
+func terminal(io.Reader) *os.File {
+	return nil
+}
+
+func tcsetpgrp(*os.File, int) error {
+	return errNotSupported
+}
+
+// exec executes the utility. Process groups are not supported.
+func (j *job) exec(ctx context.Context, c *exec.Cmd, cmd *Cmd) (int, error) {
+	err := c.Start()
+	if err == nil {
+		if cmd.started != nil {
+			cmd.started(c.Process.Pid)
+		}
+		err = c.Wait()
+	}
+	return execStatus(ctx, cmd, err)
+}
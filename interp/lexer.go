@@ -11,6 +11,7 @@
 package interp
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -58,6 +59,7 @@ var ops = map[int]string{
 }
 
 type lexer struct {
+	ctx context.Context
 	env *ExecEnv
 	r   io.RuneScanner
 	n   int
@@ -69,8 +71,9 @@ type lexer struct {
 	b strings.Builder
 }
 
-func newLexer(env *ExecEnv, r io.RuneScanner) *lexer {
+func newLexer(ctx context.Context, env *ExecEnv, r io.RuneScanner) *lexer {
 	l := &lexer{
+		ctx: ctx,
 		env: env,
 		r:   r,
 	}
@@ -79,6 +82,10 @@ func newLexer(env *ExecEnv, r io.RuneScanner) *lexer {
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
+	if err := l.ctx.Err(); err != nil {
+		l.err = err
+		return 0
+	}
 	for l.action != nil {
 		l.token = nil
 		l.action = l.action()
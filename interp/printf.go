@@ -0,0 +1,373 @@
+//
+// go.sh/interp :: printf.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// printfBuiltin writes the formatted arguments.
+func printfBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	args = args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(env.Stderr, "printf: usage: printf format [argument...]")
+		return 2, nil
+	}
+
+	p := &printf{
+		env:    env,
+		format: args[0],
+		args:   args[1:],
+	}
+	for {
+		n := len(p.args)
+		if !p.print() || len(p.args) == 0 || len(p.args) == n {
+			break
+		}
+	}
+	if _, err := io.WriteString(env.Stdout, p.b.String()); err != nil {
+		fmt.Fprintf(env.Stderr, "printf: %v\n", err)
+		return 1, nil
+	}
+	return p.status, nil
+}
+
+// printf represents the state of the printf utility.
+type printf struct {
+	env    *ExecEnv
+	format string
+	args   []string
+	b      strings.Builder
+	status int
+}
+
+// print formats the arguments once by the format. It returns false when
+// the output should be stopped.
+func (p *printf) print() bool {
+	for i := 0; i < len(p.format); {
+		switch c := p.format[i]; c {
+		case '\\':
+			n, _ := p.escape(p.format[i:], false)
+			i += n
+		case '%':
+			n, ok := p.directive(p.format[i:])
+			if !ok {
+				return false
+			}
+			i += n
+		default:
+			p.b.WriteByte(c)
+			i++
+		}
+	}
+	return true
+}
+
+// escape writes the character represented by the escape sequence at the
+// start of s, and returns the number of bytes consumed. It reports false
+// when the escape sequence is \c in the argument of %b.
+func (p *printf) escape(s string, b bool) (int, bool) {
+	if len(s) < 2 {
+		p.b.WriteByte('\\')
+		return 1, true
+	}
+	switch c := s[1]; c {
+	case '\\':
+		p.b.WriteByte('\\')
+	case 'a':
+		p.b.WriteByte('\a')
+	case 'b':
+		p.b.WriteByte('\b')
+	case 'f':
+		p.b.WriteByte('\f')
+	case 'n':
+		p.b.WriteByte('\n')
+	case 'r':
+		p.b.WriteByte('\r')
+	case 't':
+		p.b.WriteByte('\t')
+	case 'v':
+		p.b.WriteByte('\v')
+	case 'c':
+		if b {
+			return 2, false
+		}
+		p.b.WriteString(s[:2])
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		// octal
+		i := 1
+		max := 4
+		if b {
+			// \0ddd
+			if c != '0' {
+				p.b.WriteString(s[:2])
+				return 2, true
+			}
+			i++
+			max++
+		}
+		n := 0
+		for ; i < len(s) && i < max && '0' <= s[i] && s[i] <= '7'; i++ {
+			n = n*8 + int(s[i]-'0')
+		}
+		p.b.WriteByte(byte(n))
+		return i, true
+	default:
+		p.b.WriteString(s[:2])
+	}
+	return 2, true
+}
+
+// directive formats the next argument by the conversion specification at
+// the start of s, and returns the number of bytes consumed.
+func (p *printf) directive(s string) (int, bool) {
+	i := 1
+	// flags
+	var flags strings.Builder
+	for ; i < len(s) && strings.IndexByte("-+ #0", s[i]) != -1; i++ {
+		flags.WriteByte(s[i])
+	}
+	// field width
+	width := ""
+	switch {
+	case i < len(s) && s[i] == '*':
+		i++
+		n := p.int(p.next())
+		if n < 0 {
+			flags.WriteByte('-')
+			n = -n
+		}
+		width = strconv.FormatInt(n, 10)
+	default:
+		j := i
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		}
+		width = s[j:i]
+	}
+	// precision
+	prec := ""
+	if i < len(s) && s[i] == '.' {
+		i++
+		switch {
+		case i < len(s) && s[i] == '*':
+			i++
+			if n := p.int(p.next()); n >= 0 {
+				prec = "." + strconv.FormatInt(n, 10)
+			}
+		default:
+			j := i
+			for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			}
+			prec = "." + s[j:i]
+		}
+	}
+	if i == len(s) {
+		fmt.Fprintf(p.env.Stderr, "printf: %v: invalid directive\n", s)
+		p.status = 1
+		return i, false
+	}
+
+	c := s[i]
+	i++
+	spec := "%" + flags.String() + width + prec
+	switch c {
+	case '%':
+		p.b.WriteByte('%')
+	case 'd', 'i':
+		fmt.Fprintf(&p.b, spec+"d", p.int(p.next()))
+	case 'o', 'u', 'x', 'X':
+		verb := string(c)
+		if c == 'u' {
+			verb = "d"
+		}
+		fmt.Fprintf(&p.b, spec+verb, p.uint(p.next()))
+	case 'a', 'A', 'e', 'E', 'f', 'F', 'g', 'G':
+		verb := string(c)
+		switch c {
+		case 'a':
+			verb = "x"
+		case 'A':
+			verb = "X"
+		case 'g', 'G':
+			if prec == "" {
+				spec += ".6"
+			}
+		}
+		fmt.Fprintf(&p.b, spec+verb, p.float(p.next()))
+	case 'c':
+		s := p.next()
+		if s != "" {
+			_, n := utf8.DecodeRuneInString(s)
+			s = s[:n]
+		}
+		fmt.Fprintf(&p.b, spec+"s", s)
+	case 's':
+		fmt.Fprintf(&p.b, spec+"s", p.next())
+	case 'b':
+		var b strings.Builder
+		b, p.b = p.b, b
+		arg := p.next()
+		ok := true
+		for j := 0; j < len(arg) && ok; {
+			if arg[j] == '\\' {
+				var n int
+				n, ok = p.escape(arg[j:], true)
+				j += n
+			} else {
+				p.b.WriteByte(arg[j])
+				j++
+			}
+		}
+		b, p.b = p.b, b
+		fmt.Fprintf(&p.b, spec+"s", b.String())
+		return i, ok
+	default:
+		fmt.Fprintf(p.env.Stderr, "printf: %v: invalid directive\n", s[:i])
+		p.status = 1
+		return i, false
+	}
+	return i, true
+}
+
+// next returns the next argument.
+func (p *printf) next() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	s := p.args[0]
+	p.args = p.args[1:]
+	return s
+}
+
+// int converts s to a signed integer.
+func (p *printf) int(s string) int64 {
+	if r, ok := p.char(s); ok {
+		return int64(r)
+	}
+	neg, base, digits, rest := p.number(s)
+	n, err := strconv.ParseUint(digits, base, 64)
+	switch {
+	case err != nil && !errors.Is(err, strconv.ErrRange):
+	case !neg && n > 1<<63-1:
+		err = strconv.ErrRange
+		n = 1<<63 - 1
+	case neg && n > 1<<63:
+		err = strconv.ErrRange
+		n = 1 << 63
+	}
+	p.check(s, rest, err)
+	if neg {
+		return -int64(n)
+	}
+	return int64(n)
+}
+
+// uint converts s to an unsigned integer.
+func (p *printf) uint(s string) uint64 {
+	if r, ok := p.char(s); ok {
+		return uint64(r)
+	}
+	neg, base, digits, rest := p.number(s)
+	n, err := strconv.ParseUint(digits, base, 64)
+	p.check(s, rest, err)
+	if neg {
+		return -n
+	}
+	return n
+}
+
+// float converts s to a floating point number.
+func (p *printf) float(s string) float64 {
+	if r, ok := p.char(s); ok {
+		return float64(r)
+	}
+	t := strings.TrimLeft(s, " \t\n")
+	for i := len(t); i > 0; i-- {
+		if f, err := strconv.ParseFloat(t[:i], 64); err == nil || errors.Is(err, strconv.ErrRange) {
+			p.check(s, t[i:], err)
+			return f
+		}
+	}
+	p.check(s, t, nil)
+	return 0
+}
+
+// char returns the value of the character following a quote.
+func (p *printf) char(s string) (rune, bool) {
+	if len(s) > 1 && (s[0] == '\'' || s[0] == '"') {
+		r, _ := utf8.DecodeRuneInString(s[1:])
+		return r, true
+	}
+	return 0, false
+}
+
+// number splits s into its sign, base, digits, and the unconverted
+// rest.
+func (p *printf) number(s string) (neg bool, base int, digits, rest string) {
+	t := strings.TrimLeft(s, " \t\n")
+	if t != "" && (t[0] == '+' || t[0] == '-') {
+		neg = t[0] == '-'
+		t = t[1:]
+	}
+	base = 10
+	switch {
+	case len(t) > 2 && t[0] == '0' && (t[1] == 'x' || t[1] == 'X'):
+		base = 16
+		t = t[2:]
+	case len(t) > 1 && t[0] == '0':
+		base = 8
+	}
+	i := 0
+	for ; i < len(t); i++ {
+		var v int
+		switch c := t[i]; {
+		case '0' <= c && c <= '9':
+			v = int(c - '0')
+		case 'a' <= c && c <= 'f':
+			v = int(c-'a') + 10
+		case 'A' <= c && c <= 'F':
+			v = int(c-'A') + 10
+		default:
+			v = base
+		}
+		if v >= base {
+			break
+		}
+	}
+	if i == 0 {
+		return neg, base, "0", t
+	}
+	return neg, base, t[:i], t[i:]
+}
+
+// check reports an error when s was not completely converted.
+func (p *printf) check(s, rest string, err error) {
+	switch {
+	case s == "":
+		return
+	case rest == s || strings.TrimSpace(rest) == strings.TrimSpace(s):
+		fmt.Fprintf(p.env.Stderr, "printf: %v: invalid number\n", s)
+	case rest != "":
+		fmt.Fprintf(p.env.Stderr, "printf: %v: not completely converted\n", s)
+	case errors.Is(err, strconv.ErrRange):
+		fmt.Fprintf(p.env.Stderr, "printf: %v: out of range\n", s)
+	default:
+		return
+	}
+	p.status = 1
+}
@@ -0,0 +1,376 @@
+//
+// go.sh/interp :: redir.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hattya/go.sh/ast"
+)
+
+// redirect performs I/O redirections, and returns a function to restore
+// the file descriptors which were changed. If keep is true, the
+// redirections are persistent, and the files which were opened by them
+// are closed when they are no longer referenced.
+func (env *ExecEnv) redirect(ctx context.Context, redirs []*ast.Redir, keep bool) (restore func(), err error) {
+	type saved struct {
+		n  int
+		v  any
+		ok bool
+	}
+	var stack []saved
+	var files []io.Closer
+	restore = func() {
+		for i := len(stack) - 1; i >= 0; i-- {
+			env.setFd(stack[i].n, stack[i].v, stack[i].ok)
+		}
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, r := range redirs {
+		var n int
+		var loc string
+		switch {
+		case r.N == nil:
+			switch r.Op {
+			case "<", "<&", "<>", "<<", "<<-":
+				n = 0
+			default:
+				n = 1
+			}
+		case env.isPosParam(r.N.Value) || r.N.Value == "0":
+			n, err = strconv.Atoi(r.N.Value)
+			if err != nil {
+				err = RedirError{
+					Redir: r,
+					Word:  r.N.Value,
+					Msg:   "bad file descriptor",
+				}
+				restore()
+				return nil, err
+			}
+		default:
+			loc = r.N.Value
+		}
+
+		var v any
+		var f io.Closer
+		f, v, err = env.open(ctx, r)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		if f != nil {
+			files = append(files, f)
+		}
+		if loc != "" {
+			if v == nil {
+				// close the file descriptor named by the variable
+				if x, set := env.Get(loc); set {
+					if n, err = strconv.Atoi(x.Value); err == nil {
+						goto Redirect
+					}
+				}
+				err = RedirError{
+					Redir: r,
+					Word:  "{" + loc + "}",
+					Msg:   "bad file descriptor",
+				}
+				restore()
+				return nil, err
+			}
+			n = env.newFd()
+			if err = env.assign(loc, strconv.Itoa(n)); err != nil {
+				err = RedirError{
+					Redir: r,
+					Word:  "{" + loc + "}",
+					Msg:   ErrReadOnly.Error(),
+				}
+				restore()
+				return nil, err
+			}
+		}
+	Redirect:
+		old, ok := env.fd(n)
+		stack = append(stack, saved{n, old, ok})
+		if err = env.setFd(n, v, v != nil); err != nil {
+			err = RedirError{
+				Redir: r,
+				Word:  strconv.Itoa(n),
+				Msg:   err.Error(),
+			}
+			restore()
+			return nil, err
+		}
+	}
+	if keep {
+		env.files = append(env.files, files...)
+		for _, s := range stack {
+			if s.ok {
+				env.release(s.v)
+			}
+		}
+		restore = func() {}
+	}
+	return
+}
+
+// release closes the file which was opened by a persistent redirection
+// if no file descriptor refers to it.
+func (env *ExecEnv) release(v any) {
+	i := slices.IndexFunc(env.files, func(f io.Closer) bool { return f == v })
+	if i < 0 {
+		return
+	}
+	for _, x := range []any{env.Stdin, env.Stdout, env.Stderr} {
+		if x == v {
+			return
+		}
+	}
+	for _, x := range env.fds {
+		if x == v {
+			return
+		}
+	}
+	env.files[i].Close()
+	env.files = slices.Delete(env.files, i, i+1)
+}
+
+// closeFiles closes the files which were opened by the persistent
+// redirections.
+func (env *ExecEnv) closeFiles() {
+	for _, f := range env.files {
+		f.Close()
+	}
+	env.files = nil
+}
+
+// open opens the file specified by the redirection. It returns a nil
+// value when the redirection closes the file descriptor.
+func (env *ExecEnv) open(ctx context.Context, r *ast.Redir) (f io.Closer, v any, err error) {
+	var word string
+	switch r.Op {
+	case "<<", "<<-":
+		word, err = env.heredoc(ctx, r)
+		if err != nil {
+			return
+		}
+		return nil, strings.NewReader(word), nil
+	default:
+		var fields []string
+		fields, err = env.ExpandContext(ctx, r.Word, Literal)
+		if err != nil {
+			return
+		}
+		word = fields[0]
+	}
+
+	var file fs.File
+	switch r.Op {
+	case "<&", ">&":
+		// duplicate a file descriptor
+		if word == "-" {
+			return
+		}
+		var ok bool
+		if env.isPosParam(word) || word == "0" {
+			var n int
+			if n, err = strconv.Atoi(word); err == nil {
+				v, ok = env.fd(n)
+			}
+		}
+		if !ok {
+			err = RedirError{
+				Redir: r,
+				Word:  word,
+				Msg:   "bad file descriptor",
+			}
+		}
+		return
+	case "<":
+		file, err = env.openFile(word, os.O_RDONLY, 0)
+	case ">":
+		if env.Opts&NoClobber != 0 {
+			var fi fs.FileInfo
+			switch fi, err = env.stat(word); {
+			case err == nil:
+				if fi.Mode().IsRegular() {
+					err = RedirError{
+						Redir: r,
+						Word:  word,
+						Msg:   "cannot overwrite existing file",
+					}
+					return
+				}
+				file, err = env.openFile(word, os.O_WRONLY, 0o666)
+			case errors.Is(err, fs.ErrNotExist):
+				file, err = env.openFile(word, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+			}
+			break
+		}
+		fallthrough
+	case ">|":
+		file, err = env.openFile(word, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	case ">>":
+		file, err = env.openFile(word, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	case "<>":
+		file, err = env.openFile(word, os.O_RDWR|os.O_CREATE, 0o666)
+	}
+	if err != nil {
+		var perr *fs.PathError
+		if errors.As(err, &perr) {
+			err = perr.Err
+		}
+		return nil, nil, RedirError{
+			Redir: r,
+			Word:  word,
+			Msg:   err.Error(),
+		}
+	}
+	return file, file, nil
+}
+
+// heredoc expands the here-document.
+func (env *ExecEnv) heredoc(ctx context.Context, r *ast.Redir) (string, error) {
+	word := r.Heredoc
+	if r.Op == "<<-" {
+		// strip leading <tab> characters
+		word = make(ast.Word, len(r.Heredoc))
+		for i, w := range r.Heredoc {
+			if w, ok := w.(*ast.Lit); ok {
+				lines := strings.SplitAfter(w.Value, "\n")
+				for j, s := range lines {
+					if j > 0 || w.ValuePos.Col() == 1 {
+						lines[j] = strings.TrimLeft(s, "\t")
+					}
+				}
+				word[i] = &ast.Lit{
+					ValuePos: w.ValuePos,
+					Value:    strings.Join(lines, ""),
+				}
+				continue
+			}
+			word[i] = w
+		}
+	}
+	for _, w := range r.Word {
+		if _, ok := w.(*ast.Quote); ok {
+			// no expansion
+			var b strings.Builder
+			for _, w := range word {
+				b.WriteString(w.(*ast.Lit).Value)
+			}
+			return b.String(), nil
+		}
+	}
+	fields, err := env.ExpandContext(ctx, word, Literal|Quote)
+	if err != nil {
+		return "", err
+	}
+	return fields[0], nil
+}
+
+// fd returns the value of the file descriptor n.
+func (env *ExecEnv) fd(n int) (v any, ok bool) {
+	switch n {
+	case 0:
+		v = env.Stdin
+	case 1:
+		v = env.Stdout
+	case 2:
+		v = env.Stderr
+	default:
+		v, ok = env.fds[n]
+		return
+	}
+	return v, v != nil
+}
+
+// setFd sets the value of the file descriptor n. It closes the file
+// descriptor n if ok is false.
+func (env *ExecEnv) setFd(n int, v any, ok bool) error {
+	if !ok {
+		v = nil
+	}
+	switch n {
+	case 0:
+		r, ok := v.(io.Reader)
+		if v != nil && !ok {
+			return errors.New("bad file descriptor")
+		}
+		env.Stdin = r
+	case 1, 2:
+		w, ok := v.(io.Writer)
+		if v != nil && !ok {
+			return errors.New("bad file descriptor")
+		}
+		if n == 1 {
+			env.Stdout = w
+		} else {
+			env.Stderr = w
+		}
+	default:
+		if v == nil {
+			delete(env.fds, n)
+		} else {
+			if env.fds == nil {
+				env.fds = make(map[int]any)
+			}
+			env.fds[n] = v
+		}
+	}
+	return nil
+}
+
+// newFd returns the lowest unused file descriptor greater than or equal
+// to 10.
+func (env *ExecEnv) newFd() int {
+	n := 10
+	for {
+		if _, ok := env.fds[n]; !ok {
+			return n
+		}
+		n++
+	}
+}
+
+// extraFiles returns the open files for the file descriptors greater than
+// 2.
+func (env *ExecEnv) extraFiles() []*os.File {
+	var files []*os.File
+	for n, v := range env.fds {
+		if f, ok := osFile(v); ok {
+			if i := n - 3; i >= len(files) {
+				files = append(files, make([]*os.File, i-len(files)+1)...)
+			}
+			files[n-3] = f
+		}
+	}
+	return files
+}
+
+// RedirError represents an error in I/O redirection.
+type RedirError struct {
+	Redir *ast.Redir
+	Word  string
+	Msg   string
+}
+
+func (e RedirError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Word, e.Msg)
+}
@@ -0,0 +1,171 @@
+//
+// go.sh/interp :: redir_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var redirTests = []struct {
+	src    string
+	stdout string
+	status int
+	file   string
+}{
+	{"helper echo foo >$D/f", "", 0, "foo\n"},
+	{"helper echo foo >$D/f; helper cat <$D/f", "foo\n", 0, "foo\n"},
+	{"helper echo foo >$D/f; helper echo bar >$D/f", "", 0, "bar\n"},
+	{"helper echo foo >$D/f; helper echo bar >>$D/f", "", 0, "foo\nbar\n"},
+	{"helper echo foo >|$D/f", "", 0, "foo\n"},
+	{"helper echo foo 1>$D/f", "", 0, "foo\n"},
+	{"helper echo foo >$D/f; helper cat 0<$D/f", "foo\n", 0, "foo\n"},
+	{"helper echo foo >$D/f; helper cat <>$D/f", "foo\n", 0, "foo\n"},
+	{"helper echo foo 1<>$D/f", "", 0, "foo\n"},
+	{">$D/f", "", 0, ""},
+	{"FOO=foo >$D/f; helper echo $FOO", "foo\n", 0, ""},
+	// duplicate
+	{"helper echo2 foo 2>&1", "foo\n", 0, ""},
+	{"helper echo2 foo 2>$D/f", "", 0, "foo\n"},
+	{"helper echo2 foo 2>$D/f 1>&2", "", 0, "foo\n"},
+	{"helper echo foo >$D/f 2>&1", "", 0, "foo\n"},
+	{"helper echo foo 2>&1 >$D/f", "", 0, "foo\n"},
+	{"helper echo foo 3>$D/f >&3", "", 0, "foo\n"},
+	{"helper echo foo 3>$D/f >&3 3>&-", "", 0, "foo\n"},
+	{"helper echo foo >$D/f; helper cat 3<$D/f <&3", "foo\n", 0, "foo\n"},
+	{"helper echo foo >&-", "", 0, ""},
+	{"helper echo foo {fd}>$D/f >&$fd", "", 0, "foo\n"},
+	{"helper echo foo {fd}>$D/f >&$fd {fd}>&-", "", 0, "foo\n"},
+	{"helper echo foo {fd}>$D/f; helper echo $fd", "foo\n10\n", 0, ""},
+	{"readonly fd=1; helper echo foo {fd}>$D/f", "", 1, ""},
+	// compound command
+	{"{ helper echo foo; helper echo bar; } >$D/f", "", 0, "foo\nbar\n"},
+	{"(helper echo foo; helper echo bar) >$D/f", "", 0, "foo\nbar\n"},
+	{"for i in 1 2; do helper echo $i; done >$D/f", "", 0, "1\n2\n"},
+	{"helper echo foo >$D/f; while helper cat; do break; done <$D/f", "foo\n", 0, "foo\n"},
+	{"if helper exit 0; then helper echo foo; fi 2>&1 >$D/f", "", 0, "foo\n"},
+	// here-document
+	{"helper cat <<EOF\nfoo\nEOF", "foo\n", 0, ""},
+	{"FOO=foo; helper cat <<EOF\n$FOO\n\\$FOO\nEOF", "foo\n$FOO\n", 0, ""},
+	{"FOO=foo; helper cat <<\\EOF\n$FOO\nEOF", "$FOO\n", 0, ""},
+	{"FOO=foo; helper cat <<'EOF'\n$FOO\nEOF", "$FOO\n", 0, ""},
+	{"helper cat <<EOF\n\tfoo\n\tEOF\nEOF", "\tfoo\n\tEOF\n", 0, ""},
+	{"helper cat <<-EOF\n\tfoo\n\t\tbar\n\tEOF", "foo\nbar\n", 0, ""},
+	{"FOO='\tfoo'; helper cat <<-EOF\n$FOO\nEOF", "\tfoo\n", 0, ""},
+	{"helper cat <<-'EOF'\n\tfoo\n\tEOF", "foo\n", 0, ""},
+	{"helper cat <<EOF1; helper cat <<EOF2\nfoo\nEOF1\nbar\nEOF2", "foo\nbar\n", 0, ""},
+	{"helper cat 3<<EOF <&3\nfoo\nEOF", "foo\n", 0, ""},
+	{"helper echo foo >$D/f; helper cat <$D/f <<EOF\nbar\nEOF", "bar\n", 0, "foo\n"},
+	// error
+	{"helper cat <$D/f", "", 1, ""},
+	{"helper echo foo >&3", "", 1, ""},
+	{"helper echo foo 3>&-; helper echo bar >&3", "foo\n", 1, ""},
+	{"helper echo foo >&bar", "", 1, ""},
+	{"helper echo foo {fd}>&-", "", 1, ""},
+	{"helper echo2 foo 0<&2", "", 1, ""},
+}
+
+func TestRedir(t *testing.T) {
+	for _, tt := range redirTests {
+		env, stdout, _ := newTestEnv(t)
+		dir := t.TempDir()
+		env.Set("D", dir)
+		status, err := run(env, tt.src)
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		default:
+			b, _ := os.ReadFile(filepath.Join(dir, "f"))
+			if g, e := string(b), tt.file; g != e {
+				t.Errorf("%q: expected %q, got %q", tt.src, e, g)
+			}
+		}
+	}
+}
+
+func TestNoClobber(t *testing.T) {
+	env, stdout, stderr := newTestEnv(t)
+	dir := t.TempDir()
+	env.Set("D", dir)
+	env.Opts |= interp.NoClobber
+	if status, err := run(env, "helper echo foo >$D/f"); err != nil || status != 0 {
+		t.Fatalf("expected 0, got %v (%v)", status, err)
+	}
+	if status, err := run(env, "helper echo bar >$D/f"); err != nil || status != 1 {
+		t.Fatalf("expected 1, got %v (%v)", status, err)
+	}
+	if !strings.HasSuffix(stderr.String(), ": cannot overwrite existing file\n") {
+		t.Errorf("unexpected error: %q", stderr)
+	}
+	if status, err := run(env, "helper echo baz >|$D/f; helper echo qux >>$D/f; helper cat <$D/f"); err != nil || status != 0 {
+		t.Fatalf("expected 0, got %v (%v)", status, err)
+	}
+	if g, e := stdout.String(), "baz\nqux\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
+
+func TestExecFds(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("requires /proc/self/fd")
+	}
+	n := len(fds)
+	open := func() int {
+		t.Helper()
+		fds, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(fds) - n
+	}
+
+	env, _, _ := newTestEnv(t)
+	env.Set("D", t.TempDir())
+	for _, tt := range []struct {
+		src  string
+		open int
+	}{
+		{"exec 3>$D/f; exec 3>&-", 0},
+		{"exec 3>$D/f; exec 3>$D/g", 1},
+		{"exec 4>&3; exec 3>&-", 1},
+		{"exec 4>&-", 0},
+		{"exec >$D/f; exec >$D/g", 1},
+		{"exec >&-", 0},
+		{"exec 3<$D/f 3<$D/g 3<&-", 0},
+	} {
+		if _, err := run(env, tt.src); err != nil {
+			t.Fatal(err)
+		}
+		if g, e := open(), tt.open; g != e {
+			t.Errorf("%q: expected %v, got %v", tt.src, e, g)
+		}
+	}
+	// the standard output of the caller is not closed
+	f, err := os.Create(filepath.Join(t.TempDir(), "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	env.Stdout = f
+	if _, err := run(env, "exec >&-"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("foo\n"); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
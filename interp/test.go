@@ -0,0 +1,318 @@
+//
+// go.sh/interp :: test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// testBuiltin evaluates the conditional expression.
+func testBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	name := args[0]
+	args = args[1:]
+	if name == "[" {
+		if len(args) == 0 || args[len(args)-1] != "]" {
+			fmt.Fprintln(env.Stderr, "[: missing ]")
+			return 2, nil
+		}
+		args = args[:len(args)-1]
+	}
+
+	t := &tester{
+		env:  env,
+		args: args,
+	}
+	rv, err := t.eval(len(args))
+	if err == nil && len(t.args) != 0 {
+		err = fmt.Errorf("%v: unexpected operator", t.args[0])
+	}
+	switch {
+	case err != nil:
+		fmt.Fprintf(env.Stderr, "%v: %v\n", name, err)
+		return 2, nil
+	case !rv:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// tester represents the state of the test utility.
+type tester struct {
+	env  *ExecEnv
+	args []string
+}
+
+// eval evaluates the expression by the number of the arguments.
+func (t *tester) eval(n int) (bool, error) {
+	switch n {
+	case 0:
+		return false, nil
+	case 1:
+		return t.next() != "", nil
+	case 2:
+		switch {
+		case t.args[0] == "!":
+			t.next()
+			rv, err := t.eval(1)
+			return !rv, err
+		case t.isUnary(t.args[0]):
+			return t.unary()
+		}
+	case 3:
+		switch {
+		case t.isBinary(t.args[1]):
+			return t.binary()
+		case t.args[1] == "-a" || t.args[1] == "-o":
+			return t.or()
+		case t.args[0] == "!":
+			t.next()
+			rv, err := t.eval(2)
+			return !rv, err
+		case t.args[0] == "(" && t.args[2] == ")":
+			t.next()
+			rv, err := t.eval(1)
+			t.next()
+			return rv, err
+		}
+	case 4:
+		switch {
+		case t.args[0] == "!":
+			t.next()
+			rv, err := t.eval(3)
+			return !rv, err
+		case t.args[0] == "(" && t.args[3] == ")":
+			t.next()
+			rv, err := t.eval(2)
+			t.next()
+			return rv, err
+		}
+	}
+	return t.or()
+}
+
+// or evaluates the expression with the -o operator.
+func (t *tester) or() (bool, error) {
+	rv, err := t.and()
+	for err == nil && len(t.args) > 0 && t.args[0] == "-o" {
+		t.next()
+		var b bool
+		b, err = t.and()
+		rv = rv || b
+	}
+	return rv, err
+}
+
+// and evaluates the expression with the -a operator.
+func (t *tester) and() (bool, error) {
+	rv, err := t.not()
+	for err == nil && len(t.args) > 0 && t.args[0] == "-a" {
+		t.next()
+		var b bool
+		b, err = t.not()
+		rv = rv && b
+	}
+	return rv, err
+}
+
+// not evaluates the expression with the ! operator.
+func (t *tester) not() (bool, error) {
+	if len(t.args) > 1 && t.args[0] == "!" {
+		t.next()
+		rv, err := t.not()
+		return !rv, err
+	}
+	return t.primary()
+}
+
+// primary evaluates the primary expression.
+func (t *tester) primary() (bool, error) {
+	switch {
+	case len(t.args) == 0:
+		return false, errors.New("argument expected")
+	case t.args[0] == "(":
+		t.next()
+		rv, err := t.or()
+		if err == nil {
+			if len(t.args) == 0 || t.args[0] != ")" {
+				return false, errors.New("')' expected")
+			}
+			t.next()
+		}
+		return rv, err
+	case len(t.args) > 2 && t.isBinary(t.args[1]):
+		return t.binary()
+	case len(t.args) > 1 && t.isUnary(t.args[0]):
+		return t.unary()
+	}
+	return t.next() != "", nil
+}
+
+// next returns the next argument.
+func (t *tester) next() string {
+	s := t.args[0]
+	t.args = t.args[1:]
+	return s
+}
+
+// isUnary reports whether s is a unary operator.
+func (t *tester) isUnary(s string) bool {
+	return len(s) == 2 && s[0] == '-' && strings.IndexByte("bcdefghLnprsStuwxz", s[1]) != -1
+}
+
+// unary evaluates the unary expression.
+func (t *tester) unary() (bool, error) {
+	op := t.next()
+	s := t.next()
+	switch op {
+	case "-n":
+		return s != "", nil
+	case "-z":
+		return s == "", nil
+	case "-t":
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return false, fmt.Errorf("%v: integer expression expected", s)
+		}
+		v, _ := t.env.fd(n)
+		f, ok := osFile(v)
+		if !ok {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&fs.ModeCharDevice != 0, nil
+	case "-h", "-L":
+		fi, err := t.env.lstat(s)
+		return err == nil && fi.Mode()&fs.ModeSymlink != 0, nil
+	}
+
+	fi, err := t.env.stat(s)
+	if err != nil {
+		return false, nil
+	}
+	switch op {
+	case "-b":
+		return fi.Mode()&fs.ModeDevice != 0 && fi.Mode()&fs.ModeCharDevice == 0, nil
+	case "-c":
+		return fi.Mode()&fs.ModeCharDevice != 0, nil
+	case "-d":
+		return fi.IsDir(), nil
+	case "-e":
+		return true, nil
+	case "-f":
+		return fi.Mode().IsRegular(), nil
+	case "-g":
+		return fi.Mode()&fs.ModeSetgid != 0, nil
+	case "-p":
+		return fi.Mode()&fs.ModeNamedPipe != 0, nil
+	case "-r":
+		return t.access(s, fi, 4), nil
+	case "-s":
+		return fi.Size() > 0, nil
+	case "-S":
+		return fi.Mode()&fs.ModeSocket != 0, nil
+	case "-u":
+		return fi.Mode()&fs.ModeSetuid != 0, nil
+	case "-w":
+		return t.access(s, fi, 2), nil
+	case "-x":
+		return t.access(s, fi, 1), nil
+	}
+	return false, nil
+}
+
+// access reports whether the file is accessible by the mode.
+func (t *tester) access(name string, fi fs.FileInfo, mode uint32) bool {
+	if t.env.FS != nil {
+		return fi.Mode().Perm()&(fs.FileMode(mode)<<6) != 0
+	}
+	return access(name, fi, mode)
+}
+
+// isBinary reports whether s is a binary operator.
+func (t *tester) isBinary(s string) bool {
+	switch s {
+	case "=", "!=", "<", ">", "-eq", "-ne", "-gt", "-ge", "-lt", "-le", "-ef", "-nt", "-ot":
+		return true
+	}
+	return false
+}
+
+// binary evaluates the binary expression.
+func (t *tester) binary() (bool, error) {
+	l := t.next()
+	op := t.next()
+	r := t.next()
+	switch op {
+	case "=":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	case "<":
+		return l < r, nil
+	case ">":
+		return l > r, nil
+	case "-ef":
+		lfi, err := t.env.stat(l)
+		if err != nil {
+			return false, nil
+		}
+		rfi, err := t.env.stat(r)
+		return err == nil && t.env.sameFile(l, lfi, r, rfi), nil
+	case "-nt", "-ot":
+		lfi, lerr := t.env.stat(l)
+		rfi, rerr := t.env.stat(r)
+		if op == "-ot" {
+			lfi, lerr, rfi, rerr = rfi, rerr, lfi, lerr
+		}
+		switch {
+		case lerr != nil:
+			return false, nil
+		case rerr != nil:
+			return true, nil
+		}
+		return lfi.ModTime().After(rfi.ModTime()), nil
+	}
+
+	x, err := t.int(l)
+	if err != nil {
+		return false, err
+	}
+	y, err := t.int(r)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "-eq":
+		return x == y, nil
+	case "-ne":
+		return x != y, nil
+	case "-gt":
+		return x > y, nil
+	case "-ge":
+		return x >= y, nil
+	case "-lt":
+		return x < y, nil
+	default: // -le
+		return x <= y, nil
+	}
+}
+
+// int converts s to an integer.
+func (t *tester) int(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%v: integer expression expected", s)
+	}
+	return n, nil
+}
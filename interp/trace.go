@@ -0,0 +1,79 @@
+//
+// go.sh/interp :: trace.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hattya/go.sh/ast"
+)
+
+// TraceEvent represents a simple command which was executed.
+type TraceEvent struct {
+	// Pos is the position of the simple command.
+	Pos ast.Pos
+
+	// Args holds the expanded command line arguments, including the
+	// command name as Args[0]. It is empty if the simple command
+	// consists only of variable assignments.
+	Args []string
+
+	// Assigns holds the expanded variable assignments in the form
+	// "name=value".
+	Assigns []string
+
+	// Status is the exit status of the simple command.
+	Status int
+
+	// Duration is the time taken to execute the simple command.
+	Duration time.Duration
+}
+
+// xtrace writes the trace of the simple command to w, which is prefixed
+// by the expansion of the PS4 variable.
+func (env *ExecEnv) xtrace(ctx context.Context, w io.Writer, assigns, args []string) {
+	var b strings.Builder
+	b.WriteString(env.Prompt(ctx, "PS4"))
+	for i, s := range assigns {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		i := strings.IndexByte(s, '=')
+		b.WriteString(s[:i+1])
+		b.WriteString(traceQuote(s[i+1:]))
+	}
+	for i, s := range args {
+		if i > 0 || len(assigns) > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(traceQuote(s))
+	}
+	b.WriteByte('\n')
+	io.WriteString(w, b.String())
+}
+
+// traceQuote quotes s for reinput to the shell if it contains characters
+// other than the ones which are never special to the shell.
+func traceQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		switch {
+		case 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+		case strings.ContainsRune("%+,-./:=@_", r):
+		default:
+			return quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,244 @@
+//
+// go.sh/interp :: trap.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// sigRelay receives the signals for which the traps are set on a single
+// goroutine, and holds them until their actions are executed between
+// commands.
+type sigRelay struct {
+	c chan os.Signal
+
+	mu      sync.Mutex
+	ignored []os.Signal
+	pending []string
+	recv    chan struct{}
+}
+
+func newSigRelay() *sigRelay {
+	r := &sigRelay{
+		c:    make(chan os.Signal, 8),
+		recv: make(chan struct{}),
+	}
+	go r.relay()
+	return r
+}
+
+func (r *sigRelay) relay() {
+	for sig := range r.c {
+		r.mu.Lock()
+		if !slices.Contains(r.ignored, sig) {
+			if s := sigName(sig.(syscall.Signal)); !slices.Contains(r.pending, s) {
+				r.pending = append(r.pending, s)
+			}
+			close(r.recv)
+			r.recv = make(chan struct{})
+		}
+		r.mu.Unlock()
+	}
+}
+
+// notify causes the signals to be relayed. The ignored signals are also
+// caught to prevent the shell from being terminated, but discarded.
+func (r *sigRelay) notify(caught, ignored []os.Signal) {
+	signal.Stop(r.c)
+	r.mu.Lock()
+	r.ignored = ignored
+	r.mu.Unlock()
+	if sigs := append(caught, ignored...); len(sigs) > 0 {
+		signal.Notify(r.c, sigs...)
+	}
+}
+
+// received returns a channel which is closed when a signal is relayed.
+func (r *sigRelay) received() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) > 0 {
+		c := make(chan struct{})
+		close(c)
+		return c
+	}
+	return r.recv
+}
+
+// first returns the first pending signal.
+func (r *sigRelay) first() syscall.Signal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return 0
+	}
+	sig, _ := parseSignal(r.pending[0])
+	return sig
+}
+
+// take returns the names of the pending signals in the order received,
+// and clears them.
+func (r *sigRelay) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sigs := r.pending
+	r.pending = nil
+	return sigs
+}
+
+// stop stops relaying the signals.
+func (r *sigRelay) stop() {
+	signal.Stop(r.c)
+	close(r.c)
+}
+
+// setTrap sets the action for the condition. If reset is true, the
+// action is reset to the default.
+func (env *ExecEnv) setTrap(cond, action string, reset bool) {
+	if reset {
+		delete(env.traps, cond)
+	} else {
+		if env.traps == nil {
+			env.traps = make(map[string]string)
+		}
+		env.traps[cond] = action
+	}
+	env.ptraps = nil
+
+	var caught, ignored []os.Signal
+	for k, action := range env.traps {
+		switch k {
+		case "EXIT", "ERR", "KILL", "STOP":
+			continue
+		}
+		sig, ok := parseSignal(k)
+		switch {
+		case !ok:
+			// not supported on this platform
+		case action == "":
+			ignored = append(ignored, sig)
+		default:
+			caught = append(caught, sig)
+		}
+	}
+	switch {
+	case env.sig != nil:
+		env.sig.notify(caught, ignored)
+	case len(caught)+len(ignored) > 0:
+		env.sig = newSigRelay()
+		env.sig.notify(caught, ignored)
+	}
+}
+
+// stopTraps stops relaying the signals when the shell exits.
+func (env *ExecEnv) stopTraps() {
+	if env.sig != nil {
+		env.sig.stop()
+		env.sig = nil
+	}
+}
+
+// runTraps executes the actions of the traps for the pending signals.
+func (env *ExecEnv) runTraps(ctx context.Context) error {
+	if env.sig == nil {
+		return nil
+	}
+	for _, k := range env.sig.take() {
+		if action := env.traps[k]; action != "" {
+			if err := env.runTrap(ctx, action); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// errTrap executes the action of the ERR trap if the exit status is not
+// 0, and exits the shell if the errexit option is enabled. They are
+// ignored if the pipeline is a part of a condition, or the exit status of
+// the compound command is the result of a failure while they were being
+// ignored. The action is not executed again for the compound command
+// whose last command has executed it.
+func (env *ExecEnv) errTrap(ctx context.Context, status int, cond bool) error {
+	switch {
+	case status == 0 || env.errIgnored:
+		return nil
+	case cond || env.cond > 0:
+		env.errIgnored = true
+		return nil
+	}
+	if !env.errTrapped {
+		if action := env.traps["ERR"]; action != "" {
+			env.cond++
+			err := env.runTrap(ctx, action)
+			env.cond--
+			if err != nil {
+				return err
+			}
+		}
+		env.errTrapped = true
+	}
+	if env.Opts&ErrExit != 0 {
+		return &ExitError{Status: status}
+	}
+	return nil
+}
+
+// runTrap executes the action of a trap. The value of the special
+// parameter "?" and the state of the failure of the last command are
+// restored after the action was executed.
+func (env *ExecEnv) runTrap(ctx context.Context, action string) error {
+	status, ignored, trapped := env.status, env.errIgnored, env.errTrapped
+	_, err := env.source(ctx, env.Args[0], strings.NewReader(action))
+	env.status, env.errIgnored, env.errTrapped = status, ignored, trapped
+	return err
+}
+
+// interruptible returns a copy of ctx which is canceled when a signal for
+// which the trap is set is received.
+func (env *ExecEnv) interruptible(ctx context.Context) (context.Context, context.CancelFunc) {
+	if env.sig == nil {
+		return context.WithCancel(ctx)
+	}
+	r := env.sig
+	recv := r.received()
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-recv:
+			cancel(&interruptError{sig: r.first()})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// interrupted returns the exit status of the command interrupted by the
+// signal if ctx returned by interruptible was canceled by it.
+func interrupted(ctx context.Context) (int, bool) {
+	if e, ok := context.Cause(ctx).(*interruptError); ok {
+		return 128 + int(e.sig), true
+	}
+	return 0, false
+}
+
+// interruptError is the cause of the context canceled by a signal.
+type interruptError struct {
+	sig syscall.Signal
+}
+
+func (e *interruptError) Error() string {
+	return "interrupted by SIG" + sigName(e.sig)
+}
@@ -0,0 +1,54 @@
+//
+// go.sh/interp :: trap_unix_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+//go:build unix
+
+package interp_test
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var trapTests = []struct {
+	src    string
+	stdout string
+	status int
+}{
+	{"trap 'helper echo USR1' USR1; kill -USR1 $$; helper sleep 100; helper echo done", "USR1\ndone\n", 0},
+	{"trap 'helper echo USR1' USR1 USR2; kill -USR1 $$; kill -USR2 $$; helper sleep 100", "USR1\nUSR1\n", 0},
+	{"trap '' USR1; kill -USR1 $$; helper sleep 100; helper echo done", "done\n", 0},
+	{"trap 'helper echo $?' USR1; helper exit 3; kill -USR1 $$; helper sleep 100", "0\n", 0},
+	{"trap 'exit 3' USR1; kill -USR1 $$; helper sleep 100; helper echo done", "", 3},
+	{"trap 'helper echo EXIT' EXIT; trap 'exit 3' USR1; kill -USR1 $$; helper sleep 100", "EXIT\n", 3},
+	{"helper sleep 10000 & P=$!; trap 'helper echo USR1' USR1; { helper sleep 100; kill -USR1 $$; } & wait $P; S=$?; kill $P; wait; helper echo $S", fmt.Sprintf("USR1\n%v\n", 128+int(syscall.SIGUSR1)), 0},
+}
+
+func TestTrap(t *testing.T) {
+	for _, tt := range trapTests {
+		env, stdout, _ := newTestEnv(t)
+		status, err := run(env, tt.src)
+		var eerr *interp.ExitError
+		if errors.As(err, &eerr) {
+			status = eerr.Status
+			err = nil
+		}
+		switch {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.status, status)
+		case stdout.String() != tt.stdout:
+			t.Errorf("%q: expected %q, got %q", tt.src, tt.stdout, stdout)
+		}
+	}
+}
@@ -0,0 +1,859 @@
+//
+// go.sh/interp :: utility.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keywords is a list of the reserved words.
+var keywords = []string{"!", "{", "}", "case", "do", "done", "elif", "else", "esac", "fi", "for", "if", "in", "then", "until", "while"}
+
+// aliasBuiltin defines or writes the aliases.
+func aliasBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) == 1 {
+		for _, name := range slices.Sorted(maps.Keys(env.Aliases)) {
+			fmt.Fprintf(env.Stdout, "%v=%v\n", name, quote(env.Aliases[name]))
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, s := range args[1:] {
+		if name, value, ok := strings.Cut(s, "="); ok {
+			if !isAliasName(name) {
+				fmt.Fprintf(env.Stderr, "alias: %v: invalid alias name\n", name)
+				status = 1
+				continue
+			}
+			env.Aliases[name] = value
+		} else if value, ok := env.Aliases[name]; ok {
+			fmt.Fprintf(env.Stdout, "%v=%v\n", name, quote(value))
+		} else {
+			fmt.Fprintf(env.Stderr, "alias: %v: not found\n", name)
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+// isAliasName reports whether s consists of the characters which can be
+// used in an alias name.
+func isAliasName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+		case strings.ContainsRune("!%,-@_", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// cdBuiltin changes the working directory.
+func cdBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	physical := false
+	args = args[1:]
+Options:
+	for ; len(args) > 0; args = args[1:] {
+		switch s := args[0]; {
+		case s == "--":
+			args = args[1:]
+			break Options
+		case len(s) < 2 || s[0] != '-':
+			break Options
+		default:
+			for _, r := range s[1:] {
+				switch r {
+				case 'L':
+					physical = false
+				case 'P':
+					physical = true
+				default:
+					fmt.Fprintf(env.Stderr, "cd: -%c: invalid option\n", r)
+					return 2, nil
+				}
+			}
+		}
+	}
+
+	var dir string
+	print := false
+	switch len(args) {
+	case 0:
+		v, _ := env.Get("HOME")
+		if v.Value == "" {
+			fmt.Fprintln(env.Stderr, "cd: HOME not set")
+			return 1, nil
+		}
+		dir = v.Value
+	case 1:
+		dir = args[0]
+		if dir == "-" {
+			v, _ := env.Get("OLDPWD")
+			if v.Value == "" {
+				fmt.Fprintln(env.Stderr, "cd: OLDPWD not set")
+				return 1, nil
+			}
+			dir = v.Value
+			print = true
+		}
+	default:
+		fmt.Fprintln(env.Stderr, "cd: too many arguments")
+		return 2, nil
+	}
+	// search CDPATH
+	curpath := dir
+	if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, ".") {
+		if v, set := env.Get("CDPATH"); set {
+			for _, p := range filepath.SplitList(v.Value) {
+				if p == "" {
+					p = "."
+				}
+				p = filepath.Join(p, dir)
+				if fi, err := env.stat(p); err == nil && fi.IsDir() {
+					curpath = p
+					print = print || !strings.HasPrefix(p, ".")
+					break
+				}
+			}
+		}
+	}
+
+	wd := env.getwd()
+	switch {
+	case env.FS != nil:
+		if curpath = filepath.ToSlash(curpath); !path.IsAbs(curpath) {
+			curpath = path.Join(wd, curpath)
+		}
+		curpath = path.Clean(curpath)
+	case !physical:
+		curpath = filepath.Clean(env.hostName(curpath))
+	}
+	if err := env.chdir(curpath); err != nil {
+		var perr *os.PathError
+		if errors.As(err, &perr) {
+			err = perr.Err
+		}
+		fmt.Fprintf(env.Stderr, "cd: %v: %v\n", dir, err)
+		return 1, nil
+	}
+	if physical && env.FS == nil {
+		var err error
+		if curpath, err = filepath.EvalSymlinks(env.hostName(curpath)); err != nil {
+			fmt.Fprintf(env.Stderr, "cd: %v\n", err)
+			return 1, nil
+		}
+	}
+	if err := env.setwd(wd, curpath); err != nil {
+		fmt.Fprintf(env.Stderr, "cd: %v\n", err)
+		return 1, nil
+	}
+	if print {
+		fmt.Fprintln(env.Stdout, curpath)
+	}
+	return 0, nil
+}
+
+// getwd returns the working directory of the shell execution
+// environment. If FS is not nil, it returns the value of the PWD variable
+// if it is an absolute pathname of a directory in FS, otherwise returns
+// "/".
+func (env *ExecEnv) getwd() string {
+	if env.FS != nil {
+		if v, set := env.Get("PWD"); set && path.IsAbs(v.Value) && path.Clean(v.Value) == v.Value {
+			name := v.Value[1:]
+			if name == "" {
+				name = "."
+			}
+			if fi, err := fs.Stat(env.FS, name); err == nil && fi.IsDir() {
+				return v.Value
+			}
+		}
+		return "/"
+	}
+	return env.wd
+}
+
+// setwd sets the working directory, and updates the PWD and OLDPWD
+// variables.
+func (env *ExecEnv) setwd(old, wd string) error {
+	for _, v := range []Var{{Name: "OLDPWD", Value: old}, {Name: "PWD", Value: wd}} {
+		if err := env.assign(v.Name, v.Value); err != nil {
+			return err
+		}
+	}
+	if env.FS == nil {
+		env.wd = wd
+	}
+	return nil
+}
+
+// initWd returns the value of the PWD variable if it is an absolute
+// pathname of the current working directory of the process, otherwise
+// returns the current working directory of the process.
+func (env *ExecEnv) initWd() string {
+	if v, set := env.Get("PWD"); set && filepath.IsAbs(v.Value) && filepath.Clean(v.Value) == v.Value {
+		if fi, err := os.Stat(v.Value); err == nil {
+			if wd, err := os.Stat("."); err == nil && os.SameFile(fi, wd) {
+				return v.Value
+			}
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// commandBuiltin executes the simple command without the function lookup,
+// or describes how the command name is interpreted.
+func commandBuiltin(ctx context.Context, env *ExecEnv, args []string) (int, error) {
+	var std, v, V bool
+	args = args[1:]
+Options:
+	for ; len(args) > 0; args = args[1:] {
+		switch s := args[0]; {
+		case s == "--":
+			args = args[1:]
+			break Options
+		case len(s) < 2 || s[0] != '-':
+			break Options
+		default:
+			for _, r := range s[1:] {
+				switch r {
+				case 'p':
+					std = true
+				case 'v':
+					v = true
+				case 'V':
+					V = true
+				default:
+					fmt.Fprintf(env.Stderr, "command: -%c: invalid option\n", r)
+					return 2, nil
+				}
+			}
+		}
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	path := env.lookPath
+	if std {
+		path = func(name string) (string, error) {
+			return env.search(defaultPath(), name, env.findExecutable)
+		}
+	}
+	if v || V {
+		status := 0
+		for _, name := range args {
+			if !env.describe(name, V, path) {
+				status = 1
+			}
+		}
+		return status, nil
+	}
+
+	if fn, ok := spBuiltins[args[0]]; ok {
+		// a special built-in utility does not cause the shell to exit
+		status, err := fn(ctx, env, args)
+		var lc *loopCtl
+		var rc *returnCtl
+		var ee *ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &lc) || errors.As(err, &rc) || errors.As(err, &ee) || ctx.Err() != nil:
+		default:
+			fmt.Fprintln(env.Stderr, err)
+			err = nil
+		}
+		return status, err
+	}
+	if fn, ok := env.Builtins[args[0]]; ok {
+		return fn(ctx, env, args)
+	}
+	p, err := path(args[0])
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "%v: %v\n", args[0], err)
+		if errors.Is(err, os.ErrPermission) {
+			return 126, nil
+		}
+		return 127, nil
+	}
+	return env.run(ctx, p, args, nil)
+}
+
+// describe writes how the command name is interpreted. It reports whether
+// the command name is found.
+func (env *ExecEnv) describe(name string, verbose bool, lookPath func(string) (string, error)) bool {
+	var s string
+	if v, ok := env.Aliases[name]; ok {
+		if verbose {
+			s = fmt.Sprintf("%v is an alias for %v", name, v)
+		} else {
+			s = fmt.Sprintf("alias %v=%v", name, quote(v))
+		}
+	} else {
+		var kind string
+		switch {
+		case slices.Contains(keywords, name):
+			kind = "a shell keyword"
+		case spBuiltins[name] != nil:
+			kind = "a special shell builtin"
+		case env.funcs.m[name] != nil:
+			kind = "a function"
+		case env.Builtins[name] != nil:
+			kind = "a shell builtin"
+		default:
+			p, err := lookPath(name)
+			if err != nil {
+				if verbose {
+					fmt.Fprintf(env.Stderr, "%v: %v\n", name, err)
+				}
+				return false
+			}
+			if env.FS == nil {
+				p = filepath.Clean(env.hostName(p))
+			} else if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			if !verbose {
+				name = p
+			}
+			kind = p
+		}
+		if verbose {
+			s = name + " is " + kind
+		} else {
+			s = name
+		}
+	}
+	fmt.Fprintln(env.Stdout, s)
+	return true
+}
+
+// echoBuiltin writes the arguments to the standard output.
+func echoBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	args = args[1:]
+	nl := true
+	for len(args) > 0 && args[0] == "-n" {
+		args = args[1:]
+		nl = false
+	}
+	s := strings.Join(args, " ")
+	if nl {
+		s += "\n"
+	}
+	if _, err := io.WriteString(env.Stdout, s); err != nil {
+		fmt.Fprintf(env.Stderr, "echo: %v\n", err)
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// falseBuiltin returns a non-zero exit status.
+func falseBuiltin(context.Context, *ExecEnv, []string) (int, error) {
+	return 1, nil
+}
+
+// getoptsBuiltin parses the options of the positional parameters or the
+// arguments.
+func getoptsBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) < 3 {
+		fmt.Fprintln(env.Stderr, "getopts: usage: getopts optstring name [arg...]")
+		return 2, nil
+	}
+	optstring, name := args[1], args[2]
+	if !env.isName(name) {
+		fmt.Fprintf(env.Stderr, "getopts: %v: invalid name\n", name)
+		return 2, nil
+	}
+	args = args[3:]
+	if len(args) == 0 {
+		args = env.Args[1:]
+	}
+	silent := strings.HasPrefix(optstring, ":")
+	if silent {
+		optstring = optstring[1:]
+	}
+
+	optind := 1
+	if v, _ := env.Get("OPTIND"); v.Value != "" {
+		if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+			optind = n
+		}
+	}
+	if optind != env.optInd {
+		env.optPos = 0
+	}
+	var opt string
+	var optarg *string
+	var msg string
+	status := 0
+	switch {
+	case optind > len(args):
+		status = 1
+	case env.optPos == 0 && args[optind-1] == "--":
+		optind++
+		status = 1
+	case env.optPos == 0 && (len(args[optind-1]) < 2 || args[optind-1][0] != '-'):
+		status = 1
+	default:
+		arg := args[optind-1]
+		if env.optPos == 0 {
+			env.optPos = 1
+		}
+		r, n := utf8.DecodeRuneInString(arg[env.optPos:])
+		opt = string(r)
+		env.optPos += n
+		switch i := strings.IndexRune(optstring, r); {
+		case i == -1 || r == ':':
+			if silent {
+				optarg = new(string)
+				*optarg = opt
+			} else {
+				msg = "illegal option -- " + opt
+			}
+			opt = "?"
+		case strings.HasPrefix(optstring[i+n:], ":"):
+			// option-argument
+			switch {
+			case env.optPos < len(arg):
+				optarg = new(string)
+				*optarg = arg[env.optPos:]
+				env.optPos = len(arg)
+			case optind < len(args):
+				optarg = new(string)
+				*optarg = args[optind]
+				optind++
+			case silent:
+				optarg = new(string)
+				*optarg = opt
+				opt = ":"
+			default:
+				msg = "option requires an argument -- " + opt
+				opt = "?"
+			}
+		}
+		if env.optPos >= len(arg) {
+			optind++
+			env.optPos = 0
+		}
+	}
+	if status != 0 {
+		opt = "?"
+		env.optPos = 0
+	}
+	if msg != "" {
+		fmt.Fprintf(env.Stderr, "getopts: %v\n", msg)
+	}
+
+	env.optInd = optind
+	for _, v := range []Var{{Name: "OPTIND", Value: strconv.Itoa(optind)}, {Name: name, Value: opt}} {
+		if err := env.assign(v.Name, v.Value); err != nil {
+			fmt.Fprintf(env.Stderr, "getopts: %v\n", err)
+			return 2, nil
+		}
+	}
+	var err error
+	if optarg != nil {
+		err = env.assign("OPTARG", *optarg)
+	} else {
+		err = env.unset("OPTARG")
+	}
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "getopts: %v\n", err)
+		return 2, nil
+	}
+	return status, nil
+}
+
+// pwdBuiltin writes the pathname of the working directory.
+func pwdBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	physical := false
+	for _, s := range args[1:] {
+		switch s {
+		case "-L":
+			physical = false
+		case "-P":
+			physical = true
+		default:
+			fmt.Fprintf(env.Stderr, "pwd: %v: invalid option\n", s)
+			return 2, nil
+		}
+	}
+
+	var wd string
+	if physical && env.FS == nil {
+		var err error
+		if wd, err = filepath.EvalSymlinks(env.getwd()); err != nil {
+			fmt.Fprintf(env.Stderr, "pwd: %v\n", err)
+			return 1, nil
+		}
+	} else {
+		wd = env.getwd()
+	}
+	fmt.Fprintln(env.Stdout, wd)
+	return 0, nil
+}
+
+// readBuiltin reads a line from the standard input, and splits it into
+// fields.
+func readBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	raw := false
+	delim := byte('\n')
+	args = args[1:]
+Options:
+	for ; len(args) > 0; args = args[1:] {
+		switch s := args[0]; {
+		case s == "--":
+			args = args[1:]
+			break Options
+		case s == "-r":
+			raw = true
+		case s == "-d":
+			if len(args) < 2 {
+				fmt.Fprintln(env.Stderr, "read: -d: option requires an argument")
+				return 2, nil
+			}
+			args = args[1:]
+			delim = 0
+			if args[0] != "" {
+				delim = args[0][0]
+			}
+		case len(s) < 2 || s[0] != '-':
+			break Options
+		default:
+			fmt.Fprintf(env.Stderr, "read: %v: invalid option\n", s)
+			return 2, nil
+		}
+	}
+	if len(args) == 0 {
+		args = []string{"REPLY"}
+	}
+	for _, name := range args {
+		if !env.isName(name) {
+			fmt.Fprintf(env.Stderr, "read: %v: invalid name\n", name)
+			return 2, nil
+		}
+	}
+
+	// read a line
+	if env.Stdin == nil {
+		fmt.Fprintln(env.Stderr, "read: bad file descriptor")
+		return 2, nil
+	}
+	var line []byte
+	var esc []bool
+	status := 0
+	var b [1]byte
+	for bs := false; ; {
+		if _, err := io.ReadFull(env.Stdin, b[:]); err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(env.Stderr, "read: %v\n", err)
+				return 2, nil
+			}
+			status = 1
+			break
+		}
+		switch {
+		case bs:
+			bs = false
+			if b[0] != '\n' {
+				line = append(line, b[0])
+				esc = append(esc, true)
+			}
+			continue
+		case b[0] == delim:
+		case b[0] == '\\' && !raw:
+			bs = true
+			continue
+		default:
+			line = append(line, b[0])
+			esc = append(esc, false)
+			continue
+		}
+		break
+	}
+
+	// field splitting
+	ifs := IFS
+	if v, set := env.Get("IFS"); set {
+		ifs = v.Value
+	}
+	sp := fieldSplitter{line: line, esc: esc, ifs: ifs}
+	var values []string
+	if ifs == "" {
+		values = append(values, string(line))
+	} else {
+		sp.skipSpace()
+		for i := range args {
+			if i == len(args)-1 {
+				values = append(values, sp.rest())
+				break
+			}
+			values = append(values, sp.field())
+			sp.skipDelim()
+		}
+	}
+	for i, name := range args {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		if err := env.assign(name, value); err != nil {
+			fmt.Fprintf(env.Stderr, "read: %v\n", err)
+			return 2, nil
+		}
+	}
+	return status, nil
+}
+
+// fieldSplitter splits a line into fields for the read utility.
+type fieldSplitter struct {
+	line []byte
+	esc  []bool
+	ifs  string
+	i    int
+}
+
+// next returns the rune at the i-th byte, and reports whether it is an
+// unescaped IFS character and an IFS white space.
+func (sp *fieldSplitter) next(i int) (r rune, n int, delim, space bool) {
+	r, n = utf8.DecodeRune(sp.line[i:])
+	if !sp.esc[i] && strings.ContainsRune(sp.ifs, r) {
+		delim = true
+		space = r == ' ' || r == '\t' || r == '\n'
+	}
+	return
+}
+
+// skipSpace skips IFS white spaces.
+func (sp *fieldSplitter) skipSpace() {
+	for sp.i < len(sp.line) {
+		_, n, _, space := sp.next(sp.i)
+		if !space {
+			break
+		}
+		sp.i += n
+	}
+}
+
+// skipDelim skips a field delimiter.
+func (sp *fieldSplitter) skipDelim() {
+	sp.skipSpace()
+	if sp.i < len(sp.line) {
+		if _, n, delim, _ := sp.next(sp.i); delim {
+			sp.i += n
+			sp.skipSpace()
+		}
+	}
+}
+
+// field returns the next field.
+func (sp *fieldSplitter) field() string {
+	i := sp.i
+	for sp.i < len(sp.line) {
+		_, n, delim, _ := sp.next(sp.i)
+		if delim {
+			break
+		}
+		sp.i += n
+	}
+	return string(sp.line[i:sp.i])
+}
+
+// rest returns the rest of the line without trailing IFS white spaces.
+// If it consists of a field and a delimiter, the delimiter is removed.
+func (sp *fieldSplitter) rest() string {
+	i := sp.i
+	j := len(sp.line)
+	for k := i; k < len(sp.line); {
+		_, n, _, space := sp.next(k)
+		k += n
+		if !space {
+			j = k
+		}
+	}
+	if i >= j {
+		return ""
+	}
+	// a field and a delimiter
+	s := sp.field()
+	sp.skipDelim()
+	if sp.i >= j {
+		return s
+	}
+	return string(sp.line[i:j])
+}
+
+// trueBuiltin returns the exit status zero.
+func trueBuiltin(context.Context, *ExecEnv, []string) (int, error) {
+	return 0, nil
+}
+
+// typeBuiltin describes how the command names are interpreted.
+func typeBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	status := 0
+	for _, name := range args[1:] {
+		if !env.describe(name, true, env.lookPath) {
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+// umaskBuiltin sets or writes the file mode creation mask.
+func umaskBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	symbolic := false
+	args = args[1:]
+	if len(args) > 0 && args[0] == "-S" {
+		symbolic = true
+		args = args[1:]
+	}
+	switch len(args) {
+	case 0:
+		if symbolic {
+			perm := ^env.umask
+			var b strings.Builder
+			for i, who := range "ugo" {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteRune(who)
+				b.WriteByte('=')
+				shift := 6 - i*3
+				for j, r := range "rwx" {
+					if perm>>shift&(4>>j) != 0 {
+						b.WriteRune(r)
+					}
+				}
+			}
+			fmt.Fprintln(env.Stdout, b.String())
+		} else {
+			fmt.Fprintf(env.Stdout, "%04o\n", env.umask)
+		}
+		return 0, nil
+	case 1:
+	default:
+		fmt.Fprintln(env.Stderr, "umask: too many arguments")
+		return 2, nil
+	}
+
+	mask, ok := parseUmask(env.umask, args[0])
+	if !ok {
+		fmt.Fprintf(env.Stderr, "umask: %v: invalid mask\n", args[0])
+		return 1, nil
+	}
+	env.umask = mask
+	setUmask(mask)
+	return 0, nil
+}
+
+// parseUmask parses s as an octal mask or a symbolic mode.
+func parseUmask(mask int, s string) (int, bool) {
+	if s != "" && '0' <= s[0] && s[0] <= '9' {
+		n, err := strconv.ParseUint(s, 8, 0)
+		if err != nil || n > 0o777 {
+			return 0, false
+		}
+		return int(n), true
+	}
+
+	perm := ^mask & 0o777
+	for _, clause := range strings.Split(s, ",") {
+		who := 0
+		i := 0
+	Who:
+		for ; i < len(clause); i++ {
+			switch clause[i] {
+			case 'u':
+				who |= 0o700
+			case 'g':
+				who |= 0o070
+			case 'o':
+				who |= 0o007
+			case 'a':
+				who |= 0o777
+			default:
+				break Who
+			}
+		}
+		if who == 0 {
+			who = 0o777
+		}
+		if i == len(clause) {
+			return 0, false
+		}
+		for i < len(clause) {
+			op := clause[i]
+			if op != '+' && op != '-' && op != '=' {
+				return 0, false
+			}
+			bits := 0
+		Perm:
+			for i++; i < len(clause); i++ {
+				switch clause[i] {
+				case 'r':
+					bits |= 0o444
+				case 'w':
+					bits |= 0o222
+				case 'x', 'X':
+					bits |= 0o111
+				default:
+					break Perm
+				}
+			}
+			bits &= who
+			switch op {
+			case '+':
+				perm |= bits
+			case '-':
+				perm &^= bits
+			case '=':
+				perm = perm&^who | bits
+			}
+		}
+	}
+	return ^perm & 0o777, true
+}
+
+// unaliasBuiltin removes the aliases.
+func unaliasBuiltin(_ context.Context, env *ExecEnv, args []string) (int, error) {
+	if len(args) > 1 && args[1] == "-a" {
+		clear(env.Aliases)
+		return 0, nil
+	}
+
+	status := 0
+	for _, name := range args[1:] {
+		if _, ok := env.Aliases[name]; ok {
+			delete(env.Aliases, name)
+		} else {
+			fmt.Fprintf(env.Stderr, "unalias: %v: not found\n", name)
+			status = 1
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,297 @@
+//
+// go.sh/interp :: utility_test.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package interp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hattya/go.sh/interp"
+)
+
+var utilityTests = []struct {
+	src    string
+	stdin  string
+	stdout string
+	stderr string
+	status int
+}{
+	// [
+	{"[ foo ]", "", "", "", 0},
+	{"[ '' ]", "", "", "", 1},
+	{"[ foo", "", "", "[: missing ]\n", 2},
+	// alias
+	{"alias ll='echo ll'\nll", "", "ll\n", "", 0},
+	{"unalias -a; alias a=b c='d e'; alias", "", "a='b'\nc='d e'\n", "", 0},
+	{"alias a=b; alias a go.sh-not-found", "", "a='b'\n", "alias: go.sh-not-found: not found\n", 1},
+	{"alias a/b=c", "", "", "alias: a/b: invalid alias name\n", 1},
+	// cd
+	{"cd $D; pwd", "", "$D\n", "", 0},
+	{"cd $D; helper echo $PWD", "", "$D\n", "", 0},
+	{"cd $D/d; cd ..; pwd", "", "$D\n", "", 0},
+	{"cd $D; cd d; cd -", "", "$D\n", "", 0},
+	{"cd $D; cd d; cd -; helper echo $OLDPWD", "", "$D\n$D/d\n", "", 0},
+	{"HOME=$D; cd; pwd", "", "$D\n", "", 0},
+	{"CDPATH=$D; cd d", "", "$D/d\n", "", 0},
+	{"cd $D/go.sh-not-found", "", "", "cd: $D/go.sh-not-found: no such file or directory\n", 1},
+	{"cd $D/f", "", "", "cd: $D/f: not a directory\n", 1},
+	{"cd $D; helper pwd", "", "$D\n", "", 0},
+	{"cd $D; echo *", "", "d f\n", "", 0},
+	{"cd $D/d; helper echo foo >g; read X <g; echo $X *", "", "foo e g\n", "", 0},
+	{"cd $D; cd d; test -f e", "", "", "", 0},
+	{"cd $D $D", "", "", "cd: too many arguments\n", 2},
+	// command
+	{"command echo foo", "", "foo\n", "", 0},
+	{"f() { helper echo f; }; command f", "", "", "f: not found\n", 127},
+	{"command true", "", "", "", 0},
+	{"command -v cd", "", "cd\n", "", 0},
+	{"command -v if", "", "if\n", "", 0},
+	{"command -v go.sh-not-found", "", "", "", 1},
+	{"f() { :; }; command -v f", "", "f\n", "", 0},
+	{"alias() { :; }; command -V alias", "", "alias is a function\n", "", 0},
+	{"command -V :", "", ": is a special shell builtin\n", "", 0},
+	{"command -V cd", "", "cd is a shell builtin\n", "", 0},
+	{"command -V while", "", "while is a shell keyword\n", "", 0},
+	// echo
+	{"echo", "", "\n", "", 0},
+	{"echo foo bar", "", "foo bar\n", "", 0},
+	{"echo -n foo", "", "foo", "", 0},
+	{"echo -- foo", "", "-- foo\n", "", 0},
+	// false
+	{"false", "", "", "", 1},
+	// fc
+	{"fc -l", "", "", "fc: history is not available\n", 1},
+	// getopts
+	{"set -- -a -b; while getopts ab o; do echo $o $OPTIND; done; echo $OPTIND", "", "a 2\nb 3\n3\n", "", 0},
+	{"set -- -ab foo; while getopts ab o; do echo $o $OPTIND; done; shift $((OPTIND - 1)); echo $@", "", "a 1\nb 2\nfoo\n", "", 0},
+	{"set -- -a foo -b; while getopts a:b o; do echo $o $OPTARG; done", "", "a foo\nb\n", "", 0},
+	{"set -- -afoo; getopts a: o; echo $o $OPTARG", "", "a foo\n", "", 0},
+	{"set -- -- -a; getopts a o; echo $? \"$o\" $OPTIND", "", "1 ? 2\n", "", 0},
+	{"set -- foo -a; getopts a o; echo $? \"$o\" $OPTIND", "", "1 ? 1\n", "", 0},
+	{"set -- -x; getopts a o; echo \"$o\" ${OPTARG-unset}", "", "? unset\n", "getopts: illegal option -- x\n", 0},
+	{"set -- -x; getopts :a o; echo \"$o\" $OPTARG", "", "? x\n", "", 0},
+	{"set -- -a; getopts a: o; echo \"$o\"", "", "?\n", "getopts: option requires an argument -- a\n", 0},
+	{"set -- -a; getopts :a: o; echo $o $OPTARG", "", ": a\n", "", 0},
+	{"getopts ab o -b; echo $o", "", "b\n", "", 0},
+	// printf
+	{"printf foo", "", "foo", "", 0},
+	{"printf '%s\\n' foo bar", "", "foo\nbar\n", "", 0},
+	{"printf '%s %s\\n' foo bar baz", "", "foo bar\nbaz \n", "", 0},
+	{"printf '%d|%5d|%-5d|%05d|%+d\\n' 1 2 3 4 5", "", "1|    2|3    |00004|+5\n", "", 0},
+	{"printf '%*d|%.*f\\n' 3 1 2 3.14159", "", "  1|3.14\n", "", 0},
+	{"printf '%d %d %d %d\\n' 010 0x10 \"'A\" -1", "", "8 16 65 -1\n", "", 0},
+	{"printf '%o %x %X %u\\n' 8 255 255 3", "", "10 ff FF 3\n", "", 0},
+	{"printf '%.2f %e %g\\n' 1.005 1 0.0001", "", "1.00 1.000000e+00 0.0001\n", "", 0},
+	{"printf '%c%c\\n' foo bar", "", "fb\n", "", 0},
+	{"printf '%5s|%-5s|%.1s\\n' a b cd", "", "    a|b    |c\n", "", 0},
+	{"printf '%b\\n' 'a\\tb' 'c\\0101'", "", "a\tb\ncA\n", "", 0},
+	{"printf '%b' 'foo\\cbar' baz; printf '\\n'", "", "foo\n", "", 0},
+	{"printf '\\101\\t%%\\n'", "", "A\t%\n", "", 0},
+	{"printf '%d\\n'", "", "0\n", "", 0},
+	{"printf '%d\\n' foo", "", "0\n", "printf: foo: invalid number\n", 1},
+	{"printf '%d\\n' 1x", "", "1\n", "printf: 1x: not completely converted\n", 1},
+	{"printf '%d\\n' 99999999999999999999", "", "9223372036854775807\n", "printf: 99999999999999999999: out of range\n", 1},
+	{"printf '%y'", "", "", "printf: %y: invalid directive\n", 1},
+	{"printf", "", "", "printf: usage: printf format [argument...]\n", 2},
+	// pwd
+	{"cd $D; pwd -L", "", "$D\n", "", 0},
+	{"cd $D; pwd -P", "", "$D\n", "", 0},
+	// read
+	{"read FOO; echo \"$FOO\"", "foo bar\n", "foo bar\n", "", 0},
+	{"read FOO BAR; echo \"$FOO|$BAR\"", "  foo bar  baz  \n", "foo|bar  baz\n", "", 0},
+	{"read FOO BAR BAZ; echo \"$FOO|$BAR|$BAZ\"", "foo\n", "foo||\n", "", 0},
+	{"read; echo \"$REPLY\"", "foo\n", "foo\n", "", 0},
+	{"IFS=:; read FOO BAR; echo \"$FOO|$BAR\"", "foo:bar:baz\n", "foo|bar:baz\n", "", 0},
+	{"IFS=:; read FOO BAR; echo \"$FOO|$BAR\"", "foo:bar:\n", "foo|bar\n", "", 0},
+	{"IFS=; read FOO; echo \"$FOO\"", "  foo  \n", "  foo  \n", "", 0},
+	{"read FOO; echo \"$FOO\"", "foo\\ bar\\\nbaz\n", "foo barbaz\n", "", 0},
+	{"read -r FOO; echo \"$FOO\"", "foo\\ bar\\\n", "foo\\ bar\\\n", "", 0},
+	{"read FOO BAR; echo \"$FOO|$BAR\"", "foo\\ bar baz\n", "foo bar|baz\n", "", 0},
+	{"read -d : FOO; echo \"$FOO\"", "foo:bar", "foo\n", "", 0},
+	{"read FOO; echo $? \"$FOO\"", "foo", "1 foo\n", "", 0},
+	{"read FOO; echo $?", "", "1\n", "", 0},
+	{"read FOO; read BAR; echo $FOO $BAR", "foo\nbar\n", "foo bar\n", "", 0},
+	{"read 1", "", "", "read: 1: invalid name\n", 2},
+	// test
+	{"test", "", "", "", 1},
+	{"test foo", "", "", "", 0},
+	{"test -n foo", "", "", "", 0},
+	{"test -z foo", "", "", "", 1},
+	{"test ! foo", "", "", "", 1},
+	{"test foo = foo", "", "", "", 0},
+	{"test foo != foo", "", "", "", 1},
+	{"test a '<' b", "", "", "", 0},
+	{"test a '>' b", "", "", "", 1},
+	{"test 1 -eq 1", "", "", "", 0},
+	{"test 1 -ne 1", "", "", "", 1},
+	{"test 2 -gt 1", "", "", "", 0},
+	{"test 1 -ge 2", "", "", "", 1},
+	{"test 1 -lt 2", "", "", "", 0},
+	{"test 2 -le 1", "", "", "", 1},
+	{"test -n", "", "", "", 0},
+	{"test ! -n foo", "", "", "", 1},
+	{"test '(' foo ')'", "", "", "", 0},
+	{"test '(' -z foo ')'", "", "", "", 1},
+	{"test foo -a ''", "", "", "", 1},
+	{"test foo -o ''", "", "", "", 0},
+	{"test ! foo = bar", "", "", "", 0},
+	{"test foo -a '(' 1 -eq 1 -o '' ')'", "", "", "", 0},
+	{"test '' -o foo -a ''", "", "", "", 1},
+	{"test -d $D", "", "", "", 0},
+	{"test -d $D/f", "", "", "", 1},
+	{"test -e $D/f", "", "", "", 0},
+	{"test -e $D/go.sh-not-found", "", "", "", 1},
+	{"test -f $D/f", "", "", "", 0},
+	{"test -f $D", "", "", "", 1},
+	{"test -s $D/f", "", "", "", 0},
+	{"test -s $D/d/e", "", "", "", 1},
+	{"test -r $D/f", "", "", "", 0},
+	{"test -w $D/f", "", "", "", 0},
+	{"test -t 0", "", "", "", 1},
+	{"test $D/f -ef $D/f", "", "", "", 0},
+	{"test $D/f -ef $D/d/e", "", "", "", 1},
+	{"test $D/f -nt $D/go.sh-not-found", "", "", "", 0},
+	{"test $D/f -ot $D/go.sh-not-found", "", "", "", 1},
+	{"test x -eq 1", "", "", "test: x: integer expression expected\n", 2},
+	{"test -t x", "", "", "test: x: integer expression expected\n", 2},
+	{"test foo bar baz qux", "", "", "test: bar: unexpected operator\n", 2},
+	{"test '(' foo", "", "", "test: ')' expected\n", 2},
+	// true
+	{"true", "", "", "", 0},
+	// type
+	{"type cd", "", "cd is a shell builtin\n", "", 0},
+	{"type go.sh-not-found", "", "", "go.sh-not-found: not found\n", 1},
+	// umask
+	{"umask 022; umask", "", "0022\n", "", 0},
+	{"umask 027; umask -S", "", "u=rwx,g=rx,o=\n", "", 0},
+	{"umask 0; umask g-w,o=; umask", "", "0027\n", "", 0},
+	{"umask 022; (umask 077); umask", "", "0022\n", "", 0},
+	{"umask 8", "", "", "umask: 8: invalid mask\n", 1},
+	// unalias
+	{"alias a=b c=d; unalias a helper; alias", "", "c='d'\n", "", 0},
+	{"alias a=b c=d; unalias -a; alias", "", "", "", 0},
+	{"unalias go.sh-not-found", "", "", "unalias: go.sh-not-found: not found\n", 1},
+	// jobs
+	{"jobs", "", "", "", 0},
+	{"jobs -x", "", "", "jobs: -x: invalid option\n", 2},
+	{"fg", "", "", "fg: no job control\n", 1},
+	{"bg", "", "", "bg: no job control\n", 1},
+	// kill
+	{"kill -l 15", "", "TERM\n", "", 0},
+	{"kill -l 143", "", "TERM\n", "", 0},
+	{"kill -l 0", "", "", "kill: 0: invalid signal\n", 1},
+	{"kill -s FOO 1", "", "", "kill: FOO: invalid signal\n", 1},
+	{"kill -0 x", "", "", "kill: x: invalid process id\n", 1},
+	{"kill %1", "", "", "kill: %1: no such job\n", 1},
+	{"kill", "", "", "kill: missing operand\n", 2},
+	// wait
+	{"wait", "", "", "", 0},
+	{"wait 1", "", "", "", 127},
+	{"wait x", "", "", "wait: x: invalid process id\n", 1},
+	{"(exit 3) & wait -- $!", "", "", "", 3},
+	{"read X & wait $!", "foo\n", "", "", 1},
+	{"read X & wait; echo ${X-unset}", "foo\n", "unset\n", "", 0},
+}
+
+func TestUtility(t *testing.T) {
+	t.Chdir(".")
+	var mask string
+	if runtime.GOOS != "windows" {
+		env, stdout, _ := newTestEnv(t)
+		if _, err := run(env, "umask"); err != nil {
+			t.Fatal(err)
+		}
+		mask = stdout.String()
+	}
+	for _, tt := range utilityTests {
+		if runtime.GOOS == "windows" && (strings.HasPrefix(tt.src, "umask") || strings.Contains(tt.stderr, "no such file")) {
+			continue
+		}
+		env, stdout, stderr := newTestEnv(t)
+		env.Stdin = strings.NewReader(tt.stdin)
+		dir := t.TempDir()
+		if dir, err := filepath.EvalSymlinks(dir); err == nil {
+			env.Set("D", dir)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "f"), []byte("foo\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, "d"), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "d", "e"), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+		v, _ := env.Get("D")
+		expand := func(s string) string {
+			return strings.ReplaceAll(s, "$D", filepath.ToSlash(v.Value))
+		}
+		src := tt.src
+		switch status, err := run(env, src); {
+		case err != nil:
+			t.Errorf("%q: unexpected error: %v", src, err)
+		case status != tt.status:
+			t.Errorf("%q: expected %v, got %v", src, tt.status, status)
+		case filepath.ToSlash(stdout.String()) != expand(tt.stdout):
+			t.Errorf("%q: expected %q, got %q", src, expand(tt.stdout), stdout)
+		case filepath.ToSlash(stderr.String()) != expand(tt.stderr):
+			t.Errorf("%q: expected %q, got %q", src, expand(tt.stderr), stderr)
+		}
+		if mask != "" {
+			run(env, "umask "+mask)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	env, stdout, _ := newTestEnv(t)
+	env.Builtins["go.sh"] = func(_ context.Context, env *interp.ExecEnv, args []string) (int, error) {
+		env.Stdout.Write([]byte(strings.Join(args, " ") + "\n"))
+		return 3, nil
+	}
+	env.Builtins["echo"] = func(_ context.Context, env *interp.ExecEnv, args []string) (int, error) {
+		env.Stdout.Write([]byte("go.sh\n"))
+		return 0, nil
+	}
+	switch status, err := run(env, "go.sh foo bar"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 3:
+		t.Errorf("expected 3, got %v", status)
+	}
+	if _, err := run(env, "echo foo"); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := stdout.String(), "go.sh foo bar\ngo.sh\n"; g != e {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+
+	delete(env.Builtins, "echo")
+	env.Set("PATH", t.TempDir())
+	switch status, err := run(env, "echo foo"); {
+	case err != nil:
+		t.Fatal(err)
+	case status != 127:
+		t.Errorf("expected 127, got %v", status)
+	}
+	// special built-in utilities cannot be overridden
+	env.Builtins["exit"] = env.Builtins["go.sh"]
+	var eerr *interp.ExitError
+	switch _, err := run(env, "exit 5"); {
+	case !errors.As(err, &eerr):
+		t.Fatalf("expected *ExitError, got %#v", err)
+	case eerr.Status != 5:
+		t.Errorf("expected 5, got %v", eerr.Status)
+	}
+}
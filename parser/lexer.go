@@ -20,7 +20,6 @@ import (
 	"unicode"
 
 	"github.com/hattya/go.sh/ast"
-	"github.com/hattya/go.sh/interp"
 	"github.com/hattya/go.sh/printer"
 )
 
@@ -70,7 +69,7 @@ var (
 )
 
 type lexer struct {
-	env      *interp.ExecEnv
+	env      Env
 	name     string
 	r        io.RuneScanner
 	cmds     []ast.Command
@@ -97,7 +96,7 @@ type lexer struct {
 	last      ast.Pos
 }
 
-func newLexer(env *interp.ExecEnv, name string, r io.RuneScanner) *lexer {
+func newLexer(env Env, name string, r io.RuneScanner) *lexer {
 	l := &lexer{
 		env:     env,
 		name:    name,
@@ -106,6 +105,11 @@ func newLexer(env *interp.ExecEnv, name string, r io.RuneScanner) *lexer {
 		line:    1,
 		col:     1,
 	}
+	if r, ok := r.(*Reader); ok {
+		// continue from the position of the previous call
+		l.line = r.line
+		l.col = r.col
+	}
 	l.action = l.lexPipeline
 	l.mark(0)
 	return l
@@ -520,7 +524,7 @@ func (l *lexer) tr(tok int) int {
 func (l *lexer) subst() bool {
 	if l.env != nil && len(l.word) == 1 {
 		if w, ok := l.word[0].(*ast.Lit); ok {
-			if v, ok := l.env.Aliases[w.Value]; ok {
+			if v, ok := l.env.Alias(w.Value); ok {
 				// avoid infinite loop
 				for _, a := range l.aliases {
 					if a.name == w.Value {
@@ -687,7 +691,7 @@ func (l *lexer) lexHeredoc() action {
 			if l.word[i].Pos().Col() == 1 {
 				if s := l.print(l.word[i:]); strings.ContainsRune(s, '\n') {
 					break
-				} else if s == delim {
+				} else if s == delim || r.Op == "<<-" && strings.TrimLeft(s, "\t") == delim {
 					r.Heredoc = l.word[:i]
 					r.Delim = l.word[i:]
 					l.word = nil
@@ -785,6 +789,44 @@ func (l *lexer) lexHeredoc() action {
 	return l.lexToken('\n')
 }
 
+// scanWord scans runes until EOF in the same manner as the contents of
+// a here-document.
+func (l *lexer) scanWord() {
+	for {
+		r, err := l.read()
+		if err != nil {
+			break
+		}
+
+		switch r {
+		case '\\':
+			// escape character
+			if r, err = l.read(); err != nil {
+				l.b.WriteByte('\\')
+				break
+			}
+			l.esc(r)
+		case '$':
+			// parameter expansion
+			l.lit()
+			l.mark(-1)
+			if !l.scanParamExp() {
+				return
+			}
+		case '`':
+			// command substitution
+			l.lit()
+			l.mark(-1)
+			if !l.scanCmdSubst('`') {
+				return
+			}
+		default:
+			l.b.WriteRune(r)
+		}
+	}
+	l.lit()
+}
+
 func (l *lexer) scanArithExpr(pos ast.Pos) int {
 	for {
 		r, err := l.read()
@@ -1593,6 +1635,7 @@ func (l *lexer) scanCmdSubst(r rune) bool {
 		left := l.pos
 		// nest
 		ll := &lexer{
+			env:      l.env,
 			name:     l.name,
 			r:        l.r,
 			cmdSubst: r,
@@ -2,7 +2,7 @@
 //
 // go.sh/parser :: parser.go
 //
-//   Copyright (c) 2018-2024 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2018-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -19,7 +19,6 @@ import (
 	"strings"
 
 	"github.com/hattya/go.sh/ast"
-	"github.com/hattya/go.sh/interp"
 )
 
 type yySymType struct {
@@ -231,9 +230,16 @@ func location(n *ast.Lit) *ast.Lit {
 	return n
 }
 
+// Env represents a shell execution environment which is referred by the
+// parser.
+type Env interface {
+	// Alias returns the value of the alias named by the name.
+	Alias(name string) (string, bool)
+}
+
 // ParseCommands parses src, including alias substitution, and returns
 // commands.
-func ParseCommands(env *interp.ExecEnv, name string, src any) ([]ast.Command, []*ast.Comment, error) {
+func ParseCommands(env Env, name string, src any) ([]ast.Command, []*ast.Comment, error) {
 	r, err := open(src)
 	if err != nil {
 		return nil, nil, err
@@ -253,6 +259,20 @@ func ParseCommand(name string, src any) (ast.Command, []*ast.Comment, error) {
 	return cmds[0], comments, err
 }
 
+// ParseWord parses src in the same manner as the contents of a
+// here-document, and returns a word. It is used for the values of the
+// variables which are subject to expansions, such as PS1.
+func ParseWord(name string, src any) (ast.Word, error) {
+	r, err := open(src)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLexer(nil, name, r)
+	l.scanWord()
+	return l.word, l.err
+}
+
 func open(src any) (r io.RuneScanner, err error) {
 	switch src := src.(type) {
 	case []byte:
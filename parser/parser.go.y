@@ -2,7 +2,7 @@
 //
 // go.sh/parser :: parser.go
 //
-//   Copyright (c) 2018-2024 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2018-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -17,7 +17,6 @@ import (
 	"strings"
 
 	"github.com/hattya/go.sh/ast"
-	"github.com/hattya/go.sh/interp"
 )
 %}
 
@@ -803,9 +802,16 @@ func location(n *ast.Lit) *ast.Lit {
 	return n
 }
 
+// Env represents a shell execution environment which is referred by the
+// parser.
+type Env interface {
+	// Alias returns the value of the alias named by the name.
+	Alias(name string) (string, bool)
+}
+
 // ParseCommands parses src, including alias substitution, and returns
 // commands.
-func ParseCommands(env *interp.ExecEnv, name string, src any) ([]ast.Command, []*ast.Comment, error) {
+func ParseCommands(env Env, name string, src any) ([]ast.Command, []*ast.Comment, error) {
 	r, err := open(src)
 	if err != nil {
 		return nil, nil, err
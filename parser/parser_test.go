@@ -152,6 +152,27 @@ var parseCommandsTests = []struct {
 			"BAR": "echo bar; make bar\n ",
 		},
 	},
+	// command substitution
+	{
+		src: "echo $(ls)",
+		cmds: complete_commands(
+			simple_command(
+				word(lit(1, 1, "echo")),
+				word(cmd_subst(
+					true,      // dollar
+					pos(1, 7), // left
+					simple_command(
+						word(lit(1, 8, "ls")),
+						word(lit(1, 8, "-l")),
+					),
+					pos(1, 10), // right
+				)),
+			),
+		),
+		aliases: map[string]string{
+			"ls": "ls -l",
+		},
+	},
 	// for loop
 	{
 		src: "For Third",
@@ -1092,6 +1113,17 @@ var parseCommandTests = []struct {
 			),
 		),
 	},
+	{
+		src: "cat <<-EOF\n\tfoo\n\tEOF\n",
+		cmd: simple_command(
+			word(lit(1, 1, "cat")),
+			heredoc(
+				nil, 1, 5, "<<-", word(lit(1, 8, "EOF")),
+				word(lit(2, 1, "\tfoo\n")),
+				word(lit(3, 1, "\tEOF")),
+			),
+		),
+	},
 	{
 		src: "cat <<'EOF'\n\\foo\n$bar\n`baz`\nEOF\n",
 		cmd: simple_command(
@@ -3699,6 +3731,71 @@ func TestParseError(t *testing.T) {
 	}
 }
 
+var parseWordTests = []struct {
+	src  string
+	word ast.Word
+}{
+	{
+		src:  "",
+		word: nil,
+	},
+	{
+		src: "+ ",
+		word: word(
+			lit(1, 1, "+ "),
+		),
+	},
+	{
+		src: "$PWD\\$ ",
+		word: word(
+			param_exp(1, 1, false, lit(1, 2, "PWD"), nil, nil),
+			quote(1, 5, "\\", word(lit(1, 6, "$"))),
+			lit(1, 7, " "),
+		),
+	},
+	{
+		src: "'${LINENO}'\"$(echo)\"\n`echo`\\",
+		word: word(
+			lit(1, 1, "'"),
+			param_exp(1, 2, true, lit(1, 4, "LINENO"), nil, nil),
+			lit(1, 11, "'\""),
+			cmd_subst(
+				true,
+				pos(1, 14),
+				simple_command(
+					word(lit(1, 15, "echo")),
+				),
+				pos(1, 19),
+			),
+			lit(1, 20, "\"\n"),
+			cmd_subst(
+				false,
+				pos(2, 1),
+				simple_command(
+					word(lit(2, 2, "echo")),
+				),
+				pos(2, 6),
+			),
+			lit(2, 7, "\\"),
+		),
+	},
+}
+
+func TestParseWord(t *testing.T) {
+	for _, tt := range parseWordTests {
+		switch w, err := parser.ParseWord("", tt.src); {
+		case err != nil:
+			t.Error(err)
+		case !reflect.DeepEqual(w, tt.word):
+			t.Errorf("unexpected word for %q", tt.src)
+		}
+	}
+
+	if _, err := parser.ParseWord("", "${"); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestOpen(t *testing.T) {
 	for _, src := range []any{
 		[]byte{},
@@ -3762,3 +3859,30 @@ func (r *reader) Read(p []byte) (int, error) {
 	p[0], r.data = r.data[0], r.data[1:]
 	return 1, nil
 }
+
+func TestReader(t *testing.T) {
+	r, err := parser.NewReader("echo 1\n\n# 2\necho 4; echo 4\nf() {\n\techo 6\n}\n  echo 8 <<EOF\n9\nEOF\necho 11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pos []string
+	for range 8 {
+		cmds, _, err := parser.ParseCommands(nil, "", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cmd := range cmds {
+			pos = append(pos, fmt.Sprintf("%v:%v", cmd.Pos().Line(), cmd.Pos().Col()))
+		}
+	}
+	if g, e := pos, []string{"1:1", "4:1", "5:1", "8:3", "11:1"}; !reflect.DeepEqual(g, e) {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+	if g, e := r.Pos(), ast.NewPos(11, 8); g != e {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+
+	if _, err := parser.NewReader(nil); err == nil {
+		t.Error("expected error")
+	}
+}
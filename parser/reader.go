@@ -0,0 +1,66 @@
+//
+// go.sh/parser :: reader.go
+//
+//   Copyright (c) 2026 Akinori Hattori <hattya@gmail.com>
+//
+//   SPDX-License-Identifier: MIT
+//
+
+package parser
+
+import (
+	"io"
+
+	"github.com/hattya/go.sh/ast"
+)
+
+// Reader is an io.RuneScanner which keeps track of the position in the
+// source. When it is passed to ParseCommands repeatedly, the positions
+// of the commands are relative to the beginning of the source rather
+// than the beginning of each call.
+type Reader struct {
+	r         io.RuneScanner
+	line, col int
+	prev      [2]int
+}
+
+// NewReader returns a new Reader reading from src. The type of src must
+// be []byte, string, or io.Reader.
+func NewReader(src any) (*Reader, error) {
+	r, err := open(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:    r,
+		line: 1,
+		col:  1,
+	}, nil
+}
+
+// Pos returns the position of the next rune.
+func (r *Reader) Pos() ast.Pos {
+	return ast.NewPos(r.line, r.col)
+}
+
+func (r *Reader) ReadRune() (rune, int, error) {
+	c, size, err := r.r.ReadRune()
+	if err == nil {
+		r.prev = [2]int{r.line, r.col}
+		if c == '\n' {
+			r.line++
+			r.col = 1
+		} else {
+			r.col++
+		}
+	}
+	return c, size, err
+}
+
+func (r *Reader) UnreadRune() error {
+	err := r.r.UnreadRune()
+	if err == nil {
+		r.line, r.col = r.prev[0], r.prev[1]
+	}
+	return err
+}
@@ -1,7 +1,7 @@
 //
 // go.sh/pattern :: pattern.go
 //
-//   Copyright (c) 2021-2024 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -10,9 +10,13 @@
 package pattern
 
 import (
+	"context"
 	"errors"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -72,13 +76,67 @@ func Match(patterns []string, mode Mode, s string) (string, error) {
 
 // Glob returns paths that matches pattern.
 func Glob(pattern string) ([]string, error) {
+	return GlobContext(context.Background(), pattern)
+}
+
+// GlobContext is like Glob, but stops reading directories when the
+// context is done.
+func GlobContext(ctx context.Context, pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	base, pattern := split(pattern)
+	return (&globber{ctx: ctx}).glob(base, pattern)
+}
+
+// GlobDir is like Glob, but a relative pattern is resolved against the
+// directory dir instead of the current working directory. The returned
+// paths are not prefixed with dir.
+func GlobDir(dir, pattern string) ([]string, error) {
+	return GlobDirContext(context.Background(), dir, pattern)
+}
+
+// GlobDirContext is like GlobDir, but stops reading directories when the
+// context is done.
+func GlobDirContext(ctx context.Context, dir, pattern string) ([]string, error) {
 	if pattern == "" {
 		return nil, nil
 	}
 	base, pattern := split(pattern)
+	return (&globber{ctx: ctx, dir: dir}).glob(base, pattern)
+}
+
+// GlobFS is like Glob, but uses the file system fsys. The pattern is
+// interpreted as a slash-separated path from the root of fsys.
+func GlobFS(fsys fs.FS, pattern string) ([]string, error) {
+	return GlobFSContext(context.Background(), fsys, pattern)
+}
+
+// GlobFSContext is like GlobFS, but stops reading directories when the
+// context is done.
+func GlobFSContext(ctx context.Context, fsys fs.FS, pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return (&globber{ctx: ctx, fsys: fsys}).glob(".", pattern)
+}
+
+// globber represents the state of the pathname expansion. It uses the
+// file system of the host operating system if fsys is nil, and resolves
+// relative paths against dir if it is not empty.
+type globber struct {
+	ctx  context.Context
+	fsys fs.FS
+	dir  string
+}
+
+func (g *globber) glob(base, pattern string) ([]string, error) {
 	paths := []string{base}
 	for pattern != "" {
-		i, w := indexSep(pattern)
+		if err := g.ctx.Err(); err != nil {
+			return nil, err
+		}
+		i, w := g.indexSep(pattern)
 		var sep string
 		if i == -1 {
 			i = len(pattern)
@@ -97,7 +155,7 @@ func Glob(pattern string) ([]string, error) {
 					} else {
 						p += name
 					}
-					if _, err := os.Lstat(p); err == nil {
+					if g.exists(p) {
 						matches = append(matches, p+sep)
 					}
 				}
@@ -108,7 +166,7 @@ func Glob(pattern string) ([]string, error) {
 					return nil, err
 				}
 				for _, p := range paths {
-					err := glob(p, rx, func(name string) {
+					err := g.readDir(p, rx, func(name string) {
 						if p != "." {
 							name = p + name
 						}
@@ -136,25 +194,102 @@ func Glob(pattern string) ([]string, error) {
 	return paths, nil
 }
 
-func glob(path string, rx *regexp.Regexp, fn func(string)) error {
-	d, err := os.Open(path)
+func (g *globber) indexSep(pat string) (int, int) {
+	if g.fsys != nil {
+		return indexSlash(pat)
+	}
+	return indexSep(pat)
+}
+
+func (g *globber) exists(p string) bool {
+	var err error
+	switch fsys := g.fsys.(type) {
+	case nil:
+		_, err = os.Lstat(g.name(p))
+	case interface {
+		Lstat(string) (fs.FileInfo, error)
+	}:
+		_, err = fsys.Lstat(path.Clean(p))
+	default:
+		_, err = fs.Stat(fsys, path.Clean(p))
+	}
+	return err == nil
+}
+
+func (g *globber) readDir(p string, rx *regexp.Regexp, fn func(string)) error {
+	dot := strings.HasPrefix(rx.String(), `^(\.`)
+	match := func(name string) {
+		if rx.MatchString(name) && (dot || !strings.HasPrefix(name, ".")) {
+			fn(name)
+		}
+	}
+	if g.fsys != nil {
+		entries, err := fs.ReadDir(g.fsys, path.Clean(p))
+		if err != nil {
+			return nil
+		}
+		for _, e := range entries {
+			if err := g.ctx.Err(); err != nil {
+				return err
+			}
+			match(e.Name())
+		}
+		return nil
+	}
+
+	d, err := os.Open(g.name(p))
 	if err != nil {
 		return nil
 	}
 	defer d.Close()
 
-	dot := strings.HasPrefix(rx.String(), `^(\.`)
 	for {
+		if err := g.ctx.Err(); err != nil {
+			return err
+		}
 		switch n, err := d.Readdirnames(1); {
 		case err != nil:
 			if err == io.EOF {
 				return nil
 			}
 			return err
-		case rx.MatchString(n[0]):
-			if dot || !strings.HasPrefix(n[0], ".") {
-				fn(n[0])
+		default:
+			match(n[0])
+		}
+	}
+}
+
+// name returns the name of the file on the file system of the host
+// operating system.
+func (g *globber) name(p string) string {
+	switch {
+	case g.dir == "" || filepath.IsAbs(p):
+		return p
+	case filepath.VolumeName(p) == "" && os.IsPathSeparator(p[0]):
+		// rooted path on Windows
+		return filepath.VolumeName(g.dir) + p
+	case os.IsPathSeparator(g.dir[len(g.dir)-1]):
+		return g.dir + p
+	}
+	// the trailing separator is significant
+	return g.dir + string(filepath.Separator) + p
+}
+
+// indexSlash returns the index and the width of the first slash which is
+// optionally escaped by a backslash.
+func indexSlash(pat string) (int, int) {
+	n := len(pat)
+	for {
+		switch i := strings.IndexAny(pat, `/\`); {
+		case i == -1:
+			return -1, 0
+		case pat[i] == '\\' && i < len(pat)-1:
+			if pat[i+1] == '/' {
+				return n - len(pat[i:]), 2
 			}
+			pat = pat[i+1:]
+		default:
+			return n - len(pat[i:]), 1
 		}
 	}
 }
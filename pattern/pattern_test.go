@@ -1,7 +1,7 @@
 //
 // go.sh/pattern :: pattern_test.go
 //
-//   Copyright (c) 2021-2024 Akinori Hattori <hattya@gmail.com>
+//   Copyright (c) 2021-2026 Akinori Hattori <hattya@gmail.com>
 //
 //   SPDX-License-Identifier: MIT
 //
@@ -9,11 +9,13 @@
 package pattern_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/hattya/go.sh/pattern"
 )
@@ -129,6 +131,19 @@ func TestGlob(t *testing.T) {
 	}
 	defer popd()
 
+	testGlob(t, dir, pattern.Glob)
+}
+
+func TestGlobDir(t *testing.T) {
+	dir := t.TempDir()
+	testGlob(t, dir, func(pat string) ([]string, error) {
+		return pattern.GlobDir(dir, pat)
+	})
+}
+
+func testGlob(t *testing.T, dir string, glob func(string) ([]string, error)) {
+	t.Helper()
+
 	for _, p := range []string{
 		filepath.Join(".git", "config"),
 		".gitignore",
@@ -137,12 +152,10 @@ func TestGlob(t *testing.T) {
 		filepath.Join("bar", "a.go"),
 		filepath.Join("baz", "a.go"),
 	} {
-		if dir := filepath.Dir(p); dir != "" {
-			if err := mkdir(dir); err != nil {
-				t.Fatal(err)
-			}
+		if err := mkdir(dir, filepath.Dir(p)); err != nil {
+			t.Fatal(err)
 		}
-		if err := touch(p); err != nil {
+		if err := touch(dir, p); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -157,7 +170,7 @@ func TestGlob(t *testing.T) {
 		return ""
 	}
 	for _, tt := range globTests {
-		g, err := pattern.Glob(os.Expand(tt.pattern, mapper))
+		g, err := glob(os.Expand(tt.pattern, mapper))
 		if err != nil {
 			t.Error("unexpected error:", err)
 		}
@@ -166,7 +179,48 @@ func TestGlob(t *testing.T) {
 			e = append(e, os.Expand(p, mapper))
 		}
 		if !reflect.DeepEqual(g, e) {
-			t.Errorf("expected %#v, got %#v", e, g)
+			t.Errorf("%q: expected %#v, got %#v", tt.pattern, e, g)
+		}
+	}
+}
+
+var globFSTests = []struct {
+	pattern string
+	paths   []string
+}{
+	{"*.go", []string{"a.go"}},
+	{".*", []string{".git", ".gitignore"}},
+	{"*", []string{"a.go", "bar", "baz", "foo"}},
+	{"*/*", []string{"bar/a.go", "baz/a.go", "foo/a.go"}},
+	{".git/*", []string{".git/config"}},
+	{"foo/*", []string{"foo/a.go"}},
+	{"foo//*", []string{"foo//a.go"}},
+	{`foo\/*`, []string{"foo/a.go"}},
+	{`foo\*`, nil},
+	{"foo/a.go", []string{"foo/a.go"}},
+	{"foo/", []string{"foo/"}},
+	{"_/*", nil},
+	{".", []string{"."}},
+	{"..", nil},
+	{"", nil},
+}
+
+func TestGlobFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".git/config": {},
+		".gitignore":  {},
+		"a.go":        {},
+		"foo/a.go":    {},
+		"bar/a.go":    {},
+		"baz/a.go":    {},
+	}
+	for _, tt := range globFSTests {
+		g, err := pattern.GlobFS(fsys, tt.pattern)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !reflect.DeepEqual(g, tt.paths) {
+			t.Errorf("%q: expected %#v, got %#v", tt.pattern, tt.paths, g)
 		}
 	}
 }
@@ -181,6 +235,23 @@ func TestGlobError(t *testing.T) {
 		if _, err := pattern.Glob(pat); err == nil {
 			t.Error("expected error")
 		}
+		if _, err := pattern.GlobFS(fstest.MapFS{}, pat); err == nil {
+			t.Error("expected error")
+		}
+	}
+}
+
+func TestGlobContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pattern.GlobContext(ctx, "*"); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := pattern.GlobDirContext(ctx, t.TempDir(), "*"); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if _, err := pattern.GlobFSContext(ctx, fstest.MapFS{"a.go": {}}, "*"); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
 	}
 }
 